@@ -3,8 +3,15 @@ package main
 import (
 	"com.github.tunahansezen/tkube/pkg/cmd"
 	_ "com.github.tunahansezen/tkube/pkg/cmd/add"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/audit"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/cluster"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/config"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/credentials"
 	_ "com.github.tunahansezen/tkube/pkg/cmd/install"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/keepalived"
 	_ "com.github.tunahansezen/tkube/pkg/cmd/recover"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/secrets"
+	_ "com.github.tunahansezen/tkube/pkg/cmd/versions"
 	ostkube "com.github.tunahansezen/tkube/pkg/os"
 	"fmt"
 	"os"
@@ -18,9 +25,11 @@ func main() {
 	signalChannel := make(chan os.Signal, 2)
 	signal.Notify(signalChannel, os.Interrupt, syscall.SIGTERM)
 	go func() {
+		// tkube exits after the running command and cleans up temporary sudoers rules
+		ostkube.Interrupt(<-signalChannel)
 		sig := <-signalChannel
-		println(sig)
-		fmt.Println("\033[?25h") // make cursor visible
+		// stale sudoers rules left by an immediate exit are removed by the next run
+		fmt.Printf("Interrupted by %s again, exiting without cleanup\n", sig)
 		os.Exit(1)
 	}()
 	cmd.Execute(version)
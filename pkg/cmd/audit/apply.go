@@ -0,0 +1,24 @@
+package audit
+
+import (
+	"com.github.tunahansezen/tkube/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the audit apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply audit config",
+	Long:  `Write audit policy from deployment config to master nodes and restart apiservers one by one`,
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		core.PreRun()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		core.ApplyAudit()
+	},
+}
+
+func init() {
+	Cmd.AddCommand(applyCmd)
+}
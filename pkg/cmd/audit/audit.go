@@ -0,0 +1,29 @@
+package audit
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cAudit = "audit"
+)
+
+// Cmd represents the audit command
+var Cmd = &cobra.Command{
+	Use:   cAudit,
+	Short: "Manage apiserver audit logging",
+	Long:  `Manage audit logging of apiservers on master nodes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cmd.Help()
+		if err != nil {
+			os.Exit(fmt.Sprintf("Error occurred while calling help for \"%s\"", cAudit), 1)
+		}
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
@@ -0,0 +1,33 @@
+package cluster
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/core"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cCluster = "cluster"
+)
+
+// Cmd represents the cluster command
+var Cmd = &cobra.Command{
+	Use:   cCluster,
+	Short: "Manage clusters",
+	Long:  `Manage the clusters known on this machine. Each cluster has its own config, state, PKI backups, credentials and logs`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		core.PreRunCluster()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cmd.Help()
+		if err != nil {
+			os.Exit(fmt.Sprintf("Error occurred while calling help for \"%s\"", cCluster), 1)
+		}
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
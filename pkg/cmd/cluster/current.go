@@ -0,0 +1,22 @@
+package cluster
+
+import (
+	"com.github.tunahansezen/tkube/pkg/path"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// currentCmd represents the cluster current command
+var currentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Print the current cluster",
+	Long:  `Print the cluster used by commands when --cluster is not given`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(path.GetCurrentCluster())
+	},
+}
+
+func init() {
+	Cmd.AddCommand(currentCmd)
+}
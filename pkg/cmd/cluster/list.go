@@ -0,0 +1,38 @@
+package cluster
+
+import (
+	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/path"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// listCmd represents the cluster list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List clusters",
+	Long:  `List clusters known on this machine, the current cluster is marked with "*"`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		clusters, err := path.ListClusters()
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+		if len(clusters) == 0 {
+			fmt.Println("No clusters found")
+			return
+		}
+		current := path.GetCurrentCluster()
+		for _, cluster := range clusters {
+			mark := " "
+			if cluster == current {
+				mark = "*"
+			}
+			fmt.Printf("%s %s\n", mark, cluster)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+}
@@ -0,0 +1,27 @@
+package cluster
+
+import (
+	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/path"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+// useCmd represents the cluster use command
+var useCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Set the current cluster",
+	Long:  `Set the cluster used by commands when --cluster is not given`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := path.SetCurrentCluster(args[0])
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+		fmt.Printf("Switched to cluster \"%s\"\n", args[0])
+	},
+}
+
+func init() {
+	Cmd.AddCommand(useCmd)
+}
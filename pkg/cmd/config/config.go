@@ -0,0 +1,29 @@
+package config
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cConfig = "config"
+)
+
+// Cmd represents the config command
+var Cmd = &cobra.Command{
+	Use:   cConfig,
+	Short: "Manage deployment config",
+	Long:  `Migrate deployment config to the current schema version and generate its JSON Schema`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cmd.Help()
+		if err != nil {
+			os.Exit(fmt.Sprintf("Error occurred while calling help for \"%s\"", cConfig), 1)
+		}
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/core"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"github.com/spf13/cobra"
+)
+
+const (
+	fDryRun = "dry-run"
+)
+
+var (
+	dryRun bool
+)
+
+// migrateCmd represents the config migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate deployment config to the current schema version",
+	Long: `Migrate deployment config to the current schema version. The previous file is kept as a backup.
+Use --dry-run to only print the changes.`,
+	Args: cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		core.PreRunConfig()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cfg.MigrateDeploymentConfig(dryRun)
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().BoolVarP(&dryRun, fDryRun, "", false, "print the changes without writing them")
+}
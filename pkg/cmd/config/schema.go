@@ -0,0 +1,47 @@
+package config
+
+import (
+	"com.github.tunahansezen/tkube/pkg/config/schema"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+	goos "os"
+)
+
+const (
+	fOutput  = "output"
+	fsOutput = "o"
+)
+
+var (
+	schemaOutput string
+)
+
+// schemaCmd represents the config schema command
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print JSON Schema of deployment config",
+	Long: `Print JSON Schema of deployment config. Editors can use it to autocomplete and validate deployment.yaml,
+e.g. with "# yaml-language-server: $schema=<path>" at the top of the file.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := schema.DeploymentConfig()
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+		data = append(data, '\n')
+		if schemaOutput == "" {
+			fmt.Print(string(data))
+			return
+		}
+		err = goos.WriteFile(schemaOutput, data, goos.FileMode(0644))
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(schemaCmd)
+	schemaCmd.Flags().StringVarP(&schemaOutput, fOutput, fsOutput, "", "write schema to file instead of stdout")
+}
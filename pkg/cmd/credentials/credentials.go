@@ -0,0 +1,33 @@
+package credentials
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/core"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cCredentials = "credentials"
+)
+
+// Cmd represents the credentials command
+var Cmd = &cobra.Command{
+	Use:   cCredentials,
+	Short: "Manage saved credentials",
+	Long:  `Manage SSH and sudo credentials saved in the local credential store of the cluster`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		core.PreRunCluster()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cmd.Help()
+		if err != nil {
+			os.Exit(fmt.Sprintf("Error occurred while calling help for \"%s\"", cCredentials), 1)
+		}
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
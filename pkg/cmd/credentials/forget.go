@@ -0,0 +1,65 @@
+package credentials
+
+import (
+	conn "com.github.tunahansezen/tkube/pkg/connection"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	fAll = "all"
+)
+
+var (
+	forgetAll bool
+)
+
+// forgetCmd represents the credentials forget command
+var forgetCmd = &cobra.Command{
+	Use:   "forget [address...]",
+	Short: "Forget saved credentials",
+	Long:  fmt.Sprintf(`Remove saved credentials of given addresses. Use "%s" for the saved sudo password.`, sudoEntryName),
+	Args: func(cmd *cobra.Command, args []string) error {
+		if !forgetAll && len(args) == 0 {
+			return errors.New("at least one address or --all is required")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		addrs := args
+		if forgetAll {
+			list, err := conn.ListSSHData()
+			if err != nil {
+				os.Exit(err.Error(), 1)
+			}
+			addrs = nil
+			for _, data := range list {
+				addrs = append(addrs, data.Addr)
+			}
+		}
+		for _, addr := range addrs {
+			if addr == sudoEntryName {
+				addr = ""
+			}
+			removed, err := conn.ForgetSSHData(addr)
+			if err != nil {
+				os.Exit(err.Error(), 1)
+			}
+			if addr == "" {
+				addr = sudoEntryName
+			}
+			if removed {
+				fmt.Printf("Credentials of \"%s\" removed\n", addr)
+			} else {
+				fmt.Printf("No saved credentials found for \"%s\"\n", addr)
+			}
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(forgetCmd)
+	forgetCmd.Flags().BoolVarP(&forgetAll, fAll, "", false, "Forget all saved credentials")
+}
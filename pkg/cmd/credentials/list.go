@@ -0,0 +1,46 @@
+package credentials
+
+import (
+	conn "com.github.tunahansezen/tkube/pkg/connection"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	sudoEntryName = "<sudo>"
+)
+
+// listCmd represents the credentials list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved credentials",
+	Long:  `List addresses and users saved in the credential store. Secrets are never printed.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		list, err := conn.ListSSHData()
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+		if len(list) == 0 {
+			fmt.Println("No saved credentials")
+			return
+		}
+		fmt.Printf("%-20s %-16s %s\n", "ADDRESS", "USER", "AUTH")
+		for _, data := range list {
+			addr := data.Addr
+			if addr == "" {
+				addr = sudoEntryName
+			}
+			auth := "password"
+			if data.SSHPass == "" && data.SSHPrivateKeyPath != "" {
+				auth = fmt.Sprintf("private-key (%s)", data.SSHPrivateKeyPath)
+			}
+			fmt.Printf("%-20s %-16s %s\n", addr, data.SSHUser, auth)
+		}
+	},
+}
+
+func init() {
+	Cmd.AddCommand(listCmd)
+}
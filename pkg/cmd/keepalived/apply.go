@@ -0,0 +1,24 @@
+package keepalived
+
+import (
+	"com.github.tunahansezen/tkube/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+// applyCmd represents the keepalived apply command
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply keepalived config",
+	Long:  `Render keepalived config from deployment config on master nodes and reload keepalived`,
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		core.PreRun()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		core.ApplyKeepAliveD()
+	},
+}
+
+func init() {
+	Cmd.AddCommand(applyCmd)
+}
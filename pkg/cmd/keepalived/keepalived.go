@@ -0,0 +1,29 @@
+package keepalived
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cKeepalived = "keepalived"
+)
+
+// Cmd represents the keepalived command
+var Cmd = &cobra.Command{
+	Use:   cKeepalived,
+	Short: "Manage keepalived",
+	Long:  `Manage keepalived on master nodes`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cmd.Help()
+		if err != nil {
+			os.Exit(fmt.Sprintf("Error occurred while calling help for \"%s\"", cKeepalived), 1)
+		}
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
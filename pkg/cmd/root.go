@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"fmt"
+
 	"com.github.tunahansezen/tkube/pkg/core"
 	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/path"
 	"github.com/spf13/cobra"
 )
 
@@ -13,12 +16,17 @@ const (
 	fTrace             = "trace"
 	fsDebug            = "d"
 	fAuthMap           = "auth-map"
+	fAuthFile          = "auth-file"
+	fPrivilegeEsc      = "privilege-escalation"
 	fRemoteNode        = "remote"
+	fCluster           = "cluster"
+	fConfig            = "config"
 	fDockerVersion     = "docker"
 	fContainerdVersion = "containerd"
 	fEtcdVersion       = "etcd"
 	fKubeVersion       = "kube"
 	fCalicoVersion     = "calico"
+	fFlannelVersion    = "flannel"
 	fHelmVersion       = "helm"
 	fHelmfileVersion   = "helmfile"
 	fDockerPrune       = "docker-prune"
@@ -32,6 +40,25 @@ var RootCmd = &cobra.Command{
 	Short:        "tkube multi-master kubernetes installer",
 	Long:         `tkube multi-master kubernetes installer`,
 	SilenceUsage: true,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		for flag, component := range versionFlags {
+			if cmd.Flags().Changed(flag) {
+				core.SetVersionOverride(component)
+			}
+		}
+	},
+}
+
+// versionFlags override the versions recorded in deployment config
+var versionFlags = map[string]string{
+	fKubeVersion:       core.ComponentKubernetes,
+	fDockerVersion:     core.ComponentDocker,
+	fContainerdVersion: core.ComponentContainerd,
+	fEtcdVersion:       core.ComponentEtcd,
+	fCalicoVersion:     core.ComponentCalico,
+	fFlannelVersion:    core.ComponentFlannel,
+	fHelmVersion:       core.ComponentHelm,
+	fHelmfileVersion:   core.ComponentHelmfile,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -55,7 +82,17 @@ func init() {
 
 	RootCmd.PersistentFlags().BoolVarP(&core.Debug, fDebug, fsDebug, false, "debug logging for tkube")
 	RootCmd.PersistentFlags().BoolVarP(&core.Trace, fTrace, "", false, "trace logging for tkube")
+	RootCmd.PersistentFlags().StringVarP(&path.ClusterName, fCluster, "", "",
+		"name of the cluster, the current cluster is used if not set")
+	RootCmd.PersistentFlags().StringVarP(&path.ConfigFile, fConfig, "", "",
+		"deployment config file, the config of the cluster is used if not set")
 	RootCmd.PersistentFlags().StringVarP(&core.AuthMapStr, fAuthMap, "", "", "SSH auth information")
+	_ = RootCmd.PersistentFlags().MarkDeprecated(fAuthMap, fmt.Sprintf("use --%s instead", fAuthFile))
+	RootCmd.PersistentFlags().StringVarP(&core.AuthFile, fAuthFile, "", "",
+		"YAML or JSON file with SSH credentials of the nodes")
+	RootCmd.PersistentFlags().StringVarP(&os.PrivilegeEscalation, fPrivilegeEsc, "", os.EscalationDropIn,
+		fmt.Sprintf("privilege escalation mode: %s, %s or %s", os.EscalationDropIn, os.EscalationSudo,
+			os.EscalationRoot))
 	RootCmd.PersistentFlags().IPVarP(&os.RemoteNodeIP, fRemoteNode, "", nil,
 		"if node defined, remote installation will be processed")
 	RootCmd.PersistentFlags().StringVarP(&core.DockerVersion, fDockerVersion, "", core.DefaultDockerVersion,
@@ -65,9 +102,12 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(&core.EtcdVersion, fEtcdVersion, "", core.DefaultEtcdVersion,
 		"etcd version")
 	RootCmd.PersistentFlags().StringVarP(&core.KubeVersion, fKubeVersion, "", core.DefaultKubeVersion,
-		"kubernetes version")
+		fmt.Sprintf("kubernetes version, a minor version like \"1.34\" or \"%s\" is resolved to the newest patch",
+			core.LatestKubeVersion))
 	RootCmd.PersistentFlags().StringVarP(&core.CalicoVersion, fCalicoVersion, "", core.DefaultCalicoVersion,
 		"calico version")
+	RootCmd.PersistentFlags().StringVarP(&core.FlannelVersion, fFlannelVersion, "", core.DefaultFlannelVersion,
+		"flannel version")
 	RootCmd.PersistentFlags().StringVarP(&core.HelmVersion, fHelmVersion, "", core.DefaultHelmVersion,
 		"helm version")
 	RootCmd.PersistentFlags().StringVarP(&core.HelmfileVersion, fHelmfileVersion, "", core.DefaultHelmfileVersion,
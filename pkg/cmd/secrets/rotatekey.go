@@ -0,0 +1,24 @@
+package secrets
+
+import (
+	"com.github.tunahansezen/tkube/pkg/core"
+	"github.com/spf13/cobra"
+)
+
+// rotateKeyCmd represents the secrets rotate-key command
+var rotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Rotate encryption key of secrets",
+	Long:  `Add a new encryption key to master nodes, rewrite all secrets with it and remove the old keys`,
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		core.PreRun()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		core.RotateEncryptionKey()
+	},
+}
+
+func init() {
+	Cmd.AddCommand(rotateKeyCmd)
+}
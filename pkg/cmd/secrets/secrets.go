@@ -0,0 +1,29 @@
+package secrets
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cSecrets = "secrets"
+)
+
+// Cmd represents the secrets command
+var Cmd = &cobra.Command{
+	Use:   cSecrets,
+	Short: "Manage encryption of secrets",
+	Long:  `Manage encryption at rest of secrets stored in etcd`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := cmd.Help()
+		if err != nil {
+			os.Exit(fmt.Sprintf("Error occurred while calling help for \"%s\"", cSecrets), 1)
+		}
+	},
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
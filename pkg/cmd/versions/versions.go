@@ -0,0 +1,41 @@
+package versions
+
+import (
+	"com.github.tunahansezen/tkube/pkg/cmd"
+	"com.github.tunahansezen/tkube/pkg/config/compat"
+	"fmt"
+	"github.com/spf13/cobra"
+)
+
+const (
+	cVersions = "versions"
+	rowFormat = "%-12s %-8s %-8s %-18s %-12s %s\n"
+)
+
+// Cmd represents the versions command
+var Cmd = &cobra.Command{
+	Use:   cVersions,
+	Short: "List supported versions",
+	Long: `List component versions supported with each kubernetes version. Calico and flannel versions are installed
+when their versions are "auto", the others are checked before installation.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		matrix := compat.Get()
+		fmt.Printf("Minimum versions: kubernetes %s, helm %s\n\n", matrix.Minimum.Kubernetes, matrix.Minimum.Helm)
+		fmt.Printf(rowFormat, "KUBERNETES", "CALICO", "FLANNEL", "ETCD", "CONTAINERD", "DOCKER")
+		for _, r := range matrix.Kubernetes {
+			fmt.Printf(rowFormat, r.Version, r.Calico, r.Flannel, orAny(r.Etcd), orAny(r.Containerd), orAny(r.Docker))
+		}
+	},
+}
+
+func orAny(constraint string) string {
+	if constraint == "" {
+		return "any"
+	}
+	return constraint
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(Cmd)
+}
@@ -0,0 +1,167 @@
+package compat
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+)
+
+//go:generate go run -mod=vendor gen.go ../../../offline/compatibility.mk
+
+const auto = "auto"
+
+var (
+	//go:embed compatibility.yaml
+	matrixData []byte
+	matrix     Matrix
+)
+
+// Matrix is the compatibility matrix of components, releases are ordered from the newest kubernetes version.
+type Matrix struct {
+	Minimum    Minimum   `yaml:"minimum"`
+	Kubernetes []Release `yaml:"kubernetes"`
+}
+
+type Minimum struct {
+	Kubernetes string `yaml:"kubernetes"`
+	Helm       string `yaml:"helm"`
+}
+
+// Release holds the versions of a kubernetes minor version. Calico and Flannel are versions, the others are
+// constraints.
+type Release struct {
+	Version    string `yaml:"version"`
+	Calico     string `yaml:"calico"`
+	Flannel    string `yaml:"flannel"`
+	Etcd       string `yaml:"etcd,omitempty"`
+	Containerd string `yaml:"containerd,omitempty"`
+	Docker     string `yaml:"docker,omitempty"`
+}
+
+// Versions are the versions of an installation, "auto" ones are resolved by tkube.
+type Versions struct {
+	Kubernetes string
+	Helm       string
+	Etcd       string
+	Containerd string
+	Docker     string
+	Calico     string
+	Flannel    string
+	// Defaults are the components whose versions are not given explicitly, unsupported ones are only warned
+	Defaults map[string]bool
+}
+
+func init() {
+	if err := yaml.Unmarshal(matrixData, &matrix); err != nil {
+		panic(fmt.Sprintf("invalid compatibility matrix: %s", err))
+	}
+}
+
+func Get() Matrix {
+	return matrix
+}
+
+// Lookup returns the release of the minor version of kubeVersion, found is false if the matrix does not have it.
+// The newest release is returned for kubernetes versions newer than the matrix.
+func Lookup(kubeVersion string) (release Release, found bool) {
+	kubeSemVer, err := version.NewVersion(kubeVersion)
+	if err != nil {
+		return release, false
+	}
+	minor := minorVersion(kubeSemVer)
+	for _, r := range matrix.Kubernetes {
+		rSemVer := version.Must(version.NewVersion(r.Version))
+		if rSemVer.LessThanOrEqual(minor) {
+			return r, rSemVer.Equal(minor)
+		}
+	}
+	return matrix.Kubernetes[len(matrix.Kubernetes)-1], false
+}
+
+// Validate returns an error if a version is not supported with the kubernetes version. Versions of network plugins
+// other than the ones in the matrix, unsupported default versions and kubernetes versions newer than the matrix are
+// returned as warnings.
+func Validate(v Versions) (warnings []string, err error) {
+	kubeSemVer, err := version.NewVersion(v.Kubernetes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubernetes version \"%s\"", v.Kubernetes)
+	}
+	if kubeSemVer.LessThan(version.Must(version.NewVersion(matrix.Minimum.Kubernetes))) {
+		return nil, fmt.Errorf("minimum supported kubernetes version is \"%s\"", matrix.Minimum.Kubernetes)
+	}
+	if helmSemVer, err := version.NewVersion(v.Helm); err == nil &&
+		helmSemVer.LessThan(version.Must(version.NewVersion(matrix.Minimum.Helm))) {
+		return nil, fmt.Errorf("minimum supported helm version is \"%s\"", matrix.Minimum.Helm)
+	}
+	release, found := Lookup(v.Kubernetes)
+	if !found {
+		warnings = append(warnings, fmt.Sprintf("kubernetes \"%s\" is newer than the compatibility matrix, "+
+			"versions of kubernetes \"%s\" are used", v.Kubernetes, release.Version))
+	}
+	constraints := []struct{ component, version, constraint string }{
+		{"etcd", v.Etcd, release.Etcd},
+		{"containerd", v.Containerd, release.Containerd},
+		{"docker", v.Docker, release.Docker},
+	}
+	for _, c := range constraints {
+		if c.constraint == "" || c.version == "" || c.version == auto {
+			continue
+		}
+		semVer, err := version.NewVersion(c.version)
+		if err != nil {
+			return warnings, fmt.Errorf("invalid %s version \"%s\"", c.component, c.version)
+		}
+		// package revisions like 1.7.28-1 are not pre-releases
+		if version.MustConstraints(version.NewConstraint(c.constraint)).Check(semVer.Core()) {
+			continue
+		}
+		if v.Defaults[c.component] {
+			warnings = append(warnings, fmt.Sprintf("default %s \"%s\" is not supported with kubernetes \"%s\", "+
+				"supported versions: %s", c.component, c.version, v.Kubernetes, c.constraint))
+			continue
+		}
+		return warnings, fmt.Errorf("%s \"%s\" is not supported with kubernetes \"%s\", supported versions: %s",
+			c.component, c.version, v.Kubernetes, c.constraint)
+	}
+	plugins := []struct{ component, version, tested string }{
+		{"calico", v.Calico, release.Calico},
+		{"flannel", v.Flannel, release.Flannel},
+	}
+	for _, p := range plugins {
+		if p.version != "" && p.version != auto && p.version != p.tested {
+			warnings = append(warnings, fmt.Sprintf("%s \"%s\" differs from \"%s\" of kubernetes \"%s\" in the "+
+				"compatibility matrix", p.component, p.version, p.tested, release.Version))
+		}
+	}
+	return warnings, nil
+}
+
+// Makefile returns the compatibility tables of the ISO builder.
+func Makefile() []byte {
+	var b bytes.Buffer
+	b.WriteString("# Code generated by \"go generate ./pkg/config/compat\" from compatibility.yaml. DO NOT EDIT.\n")
+	tables := []struct {
+		name      string
+		component func(Release) string
+	}{
+		{"KUBE_CALICO_COMPATIBILITY", func(r Release) string { return r.Calico }},
+		{"KUBE_FLANNEL_COMPATIBILITY", func(r Release) string { return r.Flannel }},
+	}
+	for _, table := range tables {
+		entries := make([]string, 0, len(matrix.Kubernetes))
+		for _, r := range matrix.Kubernetes {
+			entries = append(entries, fmt.Sprintf("    \"%s=%s\"", r.Version, table.component(r)))
+		}
+		b.WriteString(fmt.Sprintf("\n%s := \\\n%s\n", table.name, strings.Join(entries, " \\\n")))
+	}
+	return b.Bytes()
+}
+
+func minorVersion(v *version.Version) *version.Version {
+	segments := v.Segments()
+	return version.Must(version.NewVersion(fmt.Sprintf("%d.%d", segments[0], segments[1])))
+}
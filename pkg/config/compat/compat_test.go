@@ -0,0 +1,96 @@
+package compat
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+)
+
+func TestMatrix(t *testing.T) {
+	var previous *version.Version
+	for _, r := range Get().Kubernetes {
+		v, err := version.NewVersion(r.Version)
+		if err != nil {
+			t.Fatalf("invalid kubernetes version \"%s\": %s", r.Version, err)
+		}
+		if previous != nil && !v.LessThan(previous) {
+			t.Errorf("kubernetes \"%s\" must come after \"%s\"", r.Version, previous)
+		}
+		previous = v
+		for _, constraint := range []string{r.Etcd, r.Containerd, r.Docker} {
+			if _, err = version.NewConstraint(constraint); constraint != "" && err != nil {
+				t.Errorf("invalid constraint \"%s\" of kubernetes \"%s\": %s", constraint, r.Version, err)
+			}
+		}
+		if r.Calico == "" || r.Flannel == "" {
+			t.Errorf("kubernetes \"%s\" needs calico and flannel versions", r.Version)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		kubeVersion, expected string
+		found                 bool
+	}{
+		{"1.34.2", "1.34", true},
+		{"1.17.0", "1.17", true},
+		{"1.99.0", Get().Kubernetes[0].Version, false},
+		{"1.16.15", "1.17", false},
+	}
+	for _, test := range tests {
+		release, found := Lookup(test.kubeVersion)
+		if release.Version != test.expected || found != test.found {
+			t.Errorf("Lookup(%s) = %s, %t, expected %s, %t", test.kubeVersion, release.Version, found,
+				test.expected, test.found)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		versions Versions
+		err      string
+		warnings int
+	}{
+		{"supported", Versions{Kubernetes: "1.34.2", Helm: "3.13.3", Etcd: "3.5.25", Containerd: "1.7.28-1",
+			Docker: "28.5.2", Calico: "auto", Flannel: "auto"}, "", 0},
+		{"old kubernetes", Versions{Kubernetes: "1.16.15", Helm: "3.13.3"}, "minimum supported kubernetes", 0},
+		{"old helm", Versions{Kubernetes: "1.34.2", Helm: "2.17.0"}, "minimum supported helm", 0},
+		{"old containerd", Versions{Kubernetes: "1.30.14", Helm: "3.13.3", Containerd: "1.5.11-1"},
+			"containerd \"1.5.11-1\" is not supported", 0},
+		{"new docker with dockershim", Versions{Kubernetes: "1.23.17", Helm: "3.13.3", Docker: "28.5.2"},
+			"docker \"28.5.2\" is not supported", 0},
+		{"other calico", Versions{Kubernetes: "1.34.2", Helm: "3.13.3", Calico: "3.30.0", Flannel: "auto"}, "", 1},
+		{"new kubernetes", Versions{Kubernetes: "1.99.0", Helm: "3.13.3"}, "", 1},
+		{"default flags on old kubernetes", Versions{Kubernetes: "1.23.17", Helm: "3.13.3", Etcd: "3.5.25",
+			Containerd: "auto", Docker: "28.5.2", Calico: "auto", Flannel: "auto",
+			Defaults: map[string]bool{"helm": true, "etcd": true, "containerd": true, "docker": true, "calico": true,
+				"flannel": true}}, "", 1},
+	}
+	for _, test := range tests {
+		warnings, err := Validate(test.versions)
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error containing \"%s\", got %v", test.name, test.err, err)
+		}
+		if len(warnings) != test.warnings {
+			t.Errorf("%s: expected %d warnings, got %v", test.name, test.warnings, warnings)
+		}
+	}
+}
+
+func TestMakefileUpToDate(t *testing.T) {
+	actual, err := os.ReadFile("../../../offline/compatibility.mk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(Makefile()) {
+		t.Error("offline/compatibility.mk is outdated, run \"go generate ./pkg/config/compat\"")
+	}
+}
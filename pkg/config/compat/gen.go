@@ -0,0 +1,20 @@
+//go:build ignore
+
+// gen writes the compatibility tables of the ISO builder to the file given as argument.
+package main
+
+import (
+	"log"
+	"os"
+
+	"com.github.tunahansezen/tkube/pkg/config/compat"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		log.Fatal("usage: go run gen.go <makefile>")
+	}
+	if err := os.WriteFile(os.Args[1], compat.Makefile(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strconv"
 	"strings"
 
+	"com.github.tunahansezen/tkube/pkg/config/migration"
 	"com.github.tunahansezen/tkube/pkg/config/model"
 	conn "com.github.tunahansezen/tkube/pkg/connection"
 	"com.github.tunahansezen/tkube/pkg/constant"
@@ -14,7 +15,6 @@ import (
 	"com.github.tunahansezen/tkube/pkg/path"
 	"com.github.tunahansezen/tkube/pkg/util"
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
 
@@ -34,24 +34,19 @@ func ReadConfig() error {
 }
 
 func readDeploymentConfig() error {
-	deploymentCfgFile = fmt.Sprintf("%s/%s.%s", path.GetTKubeCfgDir(), constant.DeploymentCfgName, constant.DefaultCfgType)
+	deploymentCfgFile = path.GetDeploymentCfgFile()
 	var err error
 	if os.IsFileExists("", deploymentCfgFile) {
-		viper.SetConfigName(constant.DeploymentCfgName)
-		viper.SetConfigType(constant.DefaultCfgType)
-		if os.RemoteNode == nil {
-			viper.AddConfigPath(path.GetTKubeCfgDir())
-			err = viper.ReadInConfig()
-		} else {
-			var output string
-			output = os.RunCommand(fmt.Sprintf("cat %s", deploymentCfgFile), true)
-			err = viper.ReadConfig(strings.NewReader(output))
-		}
+		output := os.RunCommand(fmt.Sprintf("cat %s", deploymentCfgFile), true)
+		log.Debugf("Using deployment config file: %s", deploymentCfgFile)
+		data, applied, err := migration.Migrate([]byte(output))
 		if err != nil {
-			return err
+			os.Exit(fmt.Sprintf("Error occurred while migrating deployment config file\n%s", err.Error()), 1)
 		}
-		log.Debugf("Using deployment config file: %s", deploymentCfgFile)
-		err = viper.Unmarshal(&DeploymentCfg, model.DeploymentCfgViperDecodeHook())
+		if len(applied) > 0 {
+			writeMigratedDeploymentConfig([]byte(output), data, applied)
+		}
+		err = yaml.Unmarshal(data, &DeploymentCfg)
 		if err != nil {
 			os.Exit(fmt.Sprintf("Error occurred while reading deployment config file\n%s", err.Error()), 1)
 		}
@@ -63,11 +58,10 @@ func readDeploymentConfig() error {
 	}
 	currentBytes, _ := yaml.Marshal(DeploymentCfg)
 	if bytes.Compare(prevBytes, currentBytes) != 0 { // config changed
-		var b bytes.Buffer
-		yamlEncoder := yaml.NewEncoder(&b)
-		yamlEncoder.SetIndent(2)
-		err = yamlEncoder.Encode(&DeploymentCfg)
-		os.CreateFile(b.Bytes(), deploymentCfgFile, os.RemoteNode.IP)
+		err = SaveDeploymentConfig()
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
 		var confirmed bool
 		confirmed, err = util.UserConfirmation("Config created or updated. Do you want to continue?")
 		if err != nil {
@@ -81,6 +75,62 @@ func readDeploymentConfig() error {
 	return nil
 }
 
+// SaveDeploymentConfig writes DeploymentCfg to the deployment config file.
+func SaveDeploymentConfig() error {
+	var b bytes.Buffer
+	yamlEncoder := yaml.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	err := yamlEncoder.Encode(&DeploymentCfg)
+	if err != nil {
+		return err
+	}
+	os.CreateFile(b.Bytes(), deploymentCfgFile, os.RemoteNode.IP)
+	return nil
+}
+
+// MigrateDeploymentConfig upgrades the deployment config file to the current schema version. With dryRun, only the
+// difference is printed.
+func MigrateDeploymentConfig(dryRun bool) error {
+	deploymentCfgFile = path.GetDeploymentCfgFile()
+	if !os.IsFileExists("", deploymentCfgFile) {
+		return fmt.Errorf("deployment config file \"%s\" not found", deploymentCfgFile)
+	}
+	output := os.RunCommand(fmt.Sprintf("cat %s", deploymentCfgFile), true)
+	data, applied, err := migration.Migrate([]byte(output))
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Printf("\"%s\" is already at \"%s\"\n", deploymentCfgFile, migration.CurrentApiVersion)
+		return nil
+	}
+	if dryRun {
+		for _, m := range applied {
+			fmt.Printf("%s -> %s: %s\n", versionName(m.From), m.To, m.Description)
+		}
+		fmt.Print(util.Diff([]byte(output), data))
+		return nil
+	}
+	writeMigratedDeploymentConfig([]byte(output), data, applied)
+	return nil
+}
+
+// writeMigratedDeploymentConfig keeps the original file as a backup next to it.
+func writeMigratedDeploymentConfig(original []byte, migrated []byte, applied []migration.Migration) {
+	backupFile := fmt.Sprintf("%s.%s.bak", deploymentCfgFile, strings.ReplaceAll(versionName(applied[0].From), "/", "-"))
+	os.CreateFile(original, backupFile, os.RemoteNode.IP)
+	os.CreateFile(migrated, deploymentCfgFile, os.RemoteNode.IP)
+	fmt.Printf("Deployment config migrated from \"%s\" to \"%s\", previous one is saved to \"%s\"\n",
+		versionName(applied[0].From), applied[len(applied)-1].To, backupFile)
+}
+
+func versionName(apiVersion string) string {
+	if apiVersion == "" {
+		return "legacy"
+	}
+	return apiVersion
+}
+
 func askDeploymentConfig() (err error) {
 	var nodes []model.KubeNode
 	addNode := true
@@ -125,13 +175,12 @@ func askDeploymentConfig() (err error) {
 	if len(nodes) != 0 {
 		DeploymentCfg.SetKubeNodes(nodes)
 	}
+	if DeploymentCfg.ApiVersion == "" {
+		DeploymentCfg.ApiVersion = migration.CurrentApiVersion
+		DeploymentCfg.Kind = migration.Kind
+	}
 	for _, node := range DeploymentCfg.GetKubeNodes() {
-		if node.SshUser != "" && node.SshPass != "" {
-			err = conn.WriteSSHData(node.IP.String(), node.SshUser, node.SshPass, node.SshPrivateKeyPath)
-			if err != nil {
-				return err
-			}
-		}
+		conn.AddDeploymentAuth(node.IP.String(), node.SshUser, node.SshPass, node.SshPrivateKeyPath)
 	}
 
 	if os.OS == os.CentOS || os.OS == os.Rocky || os.OS == os.Redhat {
@@ -181,6 +230,14 @@ func askDeploymentConfig() (err error) {
 			DeploymentCfg.Keepalived.AuthPass = "tkube"
 		}
 	}
+	if firstAsk && DeploymentCfg.Keepalived.Enabled {
+		var haproxyEnabled bool
+		haproxyEnabled, _ = util.UserConfirmation("Do you want to balance apiserver traffic with haproxy?")
+		if haproxyEnabled {
+			DeploymentCfg.LoadBalancer.Type = constant.LoadBalancerHAProxy
+			DeploymentCfg.LoadBalancer.Port = constant.DefaultLoadBalancerPort
+		}
+	}
 	if DeploymentCfg.Keepalived.Enabled {
 		if DeploymentCfg.Keepalived.VirtualRouterId == 0 {
 			var virtualRouterIdStr string
@@ -299,12 +356,7 @@ func askDeploymentConfig() (err error) {
 		}
 	}
 	if DeploymentCfg.Kubernetes.Calico.Url == "" {
-		if DeploymentCfg.Kubernetes.CalicoUrl != "" {
-			DeploymentCfg.Kubernetes.Calico.Url = DeploymentCfg.Kubernetes.CalicoUrl
-			DeploymentCfg.Kubernetes.CalicoUrl = ""
-		} else {
-			DeploymentCfg.Kubernetes.Calico.Url = constant.DefaultCalicoUrl
-		}
+		DeploymentCfg.Kubernetes.Calico.Url = constant.DefaultCalicoUrl
 	}
 	if DeploymentCfg.Kubernetes.Calico.EnvVars == nil {
 		DeploymentCfg.Kubernetes.Calico.EnvVars = []string{}
@@ -333,5 +385,45 @@ func askDeploymentConfig() (err error) {
 		aptRepo.Key = "https://customrepo.com/repository/raw/gpg-keys/customrepo.gpg"
 		DeploymentCfg.CustomRepos = []model.Repo{aptRepo}
 	}
-	return nil
+	err = DeploymentCfg.ValidateNodeSettings()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateKubelet()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateKubeadm()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateCNI()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateKubeProxy()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateAudit()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateEncryptionAtRest()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateControlPlaneEndpoint()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateControlPlaneVIP()
+	if err != nil {
+		return err
+	}
+	err = DeploymentCfg.ValidateKeepalived()
+	if err != nil {
+		return err
+	}
+	return DeploymentCfg.ValidateLoadBalancer()
 }
@@ -0,0 +1,169 @@
+package migration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ApiVersionV1 = "tkube.io/v1"
+	// CurrentApiVersion is the schema version of model.DeploymentConfig
+	CurrentApiVersion = ApiVersionV1
+	Kind              = "DeploymentConfig"
+	// legacyApiVersion stands for files written before apiVersion was introduced
+	legacyApiVersion = ""
+)
+
+// Migration converts a deployment config from one schema version to the next one. It works on the YAML node tree,
+// so comments and key order of the file are kept.
+type Migration struct {
+	From        string
+	To          string
+	Description string
+	Migrate     func(root *yaml.Node) error
+}
+
+// migrations must be ordered, each one starts from the version the previous one produced
+var migrations = []Migration{
+	{
+		From:        legacyApiVersion,
+		To:          ApiVersionV1,
+		Description: "add apiVersion and kind, move kubernetes.calicoUrl to kubernetes.calico.url, rename keepalived.authpass to keepalived.authPass",
+		Migrate:     migrateLegacyToV1,
+	},
+}
+
+// Migrate upgrades data to CurrentApiVersion and returns the applied migrations. data is returned as is if it is
+// already up-to-date.
+func Migrate(data []byte) ([]byte, []Migration, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if doc.Kind == 0 { // empty file
+		return data, nil, nil
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, errors.New("deployment config must be a YAML mapping")
+	}
+	root := doc.Content[0]
+	if kind := getValue(root, "kind"); kind != nil && kind.Value != Kind {
+		return nil, nil, fmt.Errorf("unexpected kind \"%s\", expected \"%s\"", kind.Value, Kind)
+	}
+	current := legacyApiVersion
+	if apiVersion := getValue(root, "apiVersion"); apiVersion != nil {
+		current = apiVersion.Value
+	}
+	var applied []Migration
+	for current != CurrentApiVersion {
+		migration := find(current)
+		if migration == nil {
+			return nil, nil, fmt.Errorf("unsupported apiVersion \"%s\", it may be written by a newer tkube version",
+				current)
+		}
+		err = migration.Migrate(root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migration from \"%s\" to \"%s\" failed: %w", migration.From, migration.To,
+				err)
+		}
+		setValue(root, "apiVersion", migration.To)
+		applied = append(applied, *migration)
+		current = migration.To
+	}
+	if len(applied) == 0 {
+		return data, nil, nil
+	}
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.Bytes(), applied, nil
+}
+
+func find(from string) *Migration {
+	for i := range migrations {
+		if migrations[i].From == from {
+			return &migrations[i]
+		}
+	}
+	return nil
+}
+
+func migrateLegacyToV1(root *yaml.Node) error {
+	// header goes to the top of the file
+	header := []*yaml.Node{
+		scalar("apiVersion"), scalar(ApiVersionV1),
+		scalar("kind"), scalar(Kind),
+	}
+	removeKey(root, "apiVersion")
+	removeKey(root, "kind")
+	if len(root.Content) > 0 {
+		// keep the comment at the top of the file above the header
+		header[0].HeadComment = root.Content[0].HeadComment
+		root.Content[0].HeadComment = ""
+	}
+	root.Content = append(header, root.Content...)
+
+	if kubernetes := getValue(root, "kubernetes"); kubernetes != nil && kubernetes.Kind == yaml.MappingNode {
+		if calicoUrl := getValue(kubernetes, "calicoUrl"); calicoUrl != nil {
+			calico := getValue(kubernetes, "calico")
+			if calico == nil {
+				calico = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+				kubernetes.Content = append(kubernetes.Content, scalar("calico"), calico)
+			}
+			if url := getValue(calico, "url"); url == nil || url.Value == "" {
+				setValue(calico, "url", calicoUrl.Value)
+			}
+			removeKey(kubernetes, "calicoUrl")
+		}
+	}
+	if keepalived := getValue(root, "keepalived"); keepalived != nil && keepalived.Kind == yaml.MappingNode {
+		if authPass := getValue(keepalived, "authpass"); authPass != nil {
+			if getValue(keepalived, "authPass") == nil {
+				setValue(keepalived, "authPass", authPass.Value)
+			}
+			removeKey(keepalived, "authpass")
+		}
+	}
+	return nil
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func getValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func setValue(mapping *yaml.Node, key string, value string) {
+	if node := getValue(mapping, key); node != nil {
+		node.Kind = yaml.ScalarNode
+		node.Tag = "!!str"
+		node.Value = value
+		node.Content = nil
+		return
+	}
+	mapping.Content = append(mapping.Content, scalar(key), scalar(value))
+}
+
+func removeKey(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateLegacy(t *testing.T) {
+	legacy := `# cluster of the lab
+nodes:
+  - hostname: node1
+    IP: 192.168.50.10
+keepalived:
+  authpass: secret
+kubernetes:
+  calicoUrl: https://example.com/calico-{version}.yaml
+`
+	migrated, applied, err := Migrate([]byte(legacy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 1 || applied[0].To != CurrentApiVersion {
+		t.Fatalf("unexpected migrations: %v", applied)
+	}
+	if !strings.HasPrefix(string(migrated), "# cluster of the lab\napiVersion: "+ApiVersionV1+"\nkind: "+Kind) {
+		t.Errorf("header is not at the top:\n%s", migrated)
+	}
+	var cfg struct {
+		Keepalived struct {
+			AuthPass string `yaml:"authPass"`
+			Legacy   string `yaml:"authpass"`
+		} `yaml:"keepalived"`
+		Kubernetes struct {
+			CalicoUrl string `yaml:"calicoUrl"`
+			Calico    struct {
+				Url string `yaml:"url"`
+			} `yaml:"calico"`
+		} `yaml:"kubernetes"`
+	}
+	if err = yaml.Unmarshal(migrated, &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Keepalived.AuthPass != "secret" || cfg.Keepalived.Legacy != "" {
+		t.Errorf("authpass not migrated: %+v", cfg.Keepalived)
+	}
+	if cfg.Kubernetes.Calico.Url != "https://example.com/calico-{version}.yaml" || cfg.Kubernetes.CalicoUrl != "" {
+		t.Errorf("calicoUrl not migrated: %+v", cfg.Kubernetes)
+	}
+}
+
+func TestMigrateCurrent(t *testing.T) {
+	current := "apiVersion: " + CurrentApiVersion + "\nkind: " + Kind + "\nnodes: []\n"
+	migrated, applied, err := Migrate([]byte(current))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 0 || string(migrated) != current {
+		t.Errorf("up-to-date config changed:\n%s", migrated)
+	}
+}
+
+func TestMigrateUnknown(t *testing.T) {
+	if _, _, err := Migrate([]byte("apiVersion: tkube.io/v99\nkind: " + Kind + "\n")); err == nil {
+		t.Error("expected error for unknown apiVersion")
+	}
+	if _, _, err := Migrate([]byte("apiVersion: " + CurrentApiVersion + "\nkind: Other\n")); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	AuditPresetDefault  = "default"
+	AuditPresetMetadata = "metadata"
+	AuditDir            = "/etc/kubernetes/audit"
+	AuditPolicyFile     = AuditDir + "/policy.yaml"
+	AuditWebhookFile    = AuditDir + "/webhook.kubeconfig"
+	defaultAuditLogPath = "/var/log/kubernetes/audit/audit.log"
+	defaultAuditMaxAge  = 30
+	defaultAuditBackup  = 10
+	defaultAuditMaxSize = 100
+)
+
+var (
+	auditPresets      = []string{AuditPresetDefault, AuditPresetMetadata}
+	auditWebhookModes = []string{"batch", "blocking", "blocking-strict"}
+)
+
+type Audit struct {
+	Enabled bool `yaml:"enabled"`
+	// Policy is the content of the audit policy, Preset is used if it is empty
+	Policy    string        `yaml:"policy,omitempty"`
+	Preset    string        `yaml:"preset,omitempty"`
+	LogPath   string        `yaml:"logPath,omitempty"`
+	MaxAge    int           `yaml:"maxAge,omitempty"`
+	MaxBackup int           `yaml:"maxBackup,omitempty"`
+	MaxSize   int           `yaml:"maxSize,omitempty"`
+	Webhook   *AuditWebhook `yaml:"webhook,omitempty"`
+}
+
+// AuditWebhook sends audit events to a remote API besides the log file.
+type AuditWebhook struct {
+	// KubeConfig is the content of the kubeconfig file pointing to the webhook
+	KubeConfig string `yaml:"kubeConfig"`
+	Mode       string `yaml:"mode,omitempty"`
+}
+
+func (a Audit) GetPreset() string {
+	if a.Preset == "" {
+		return AuditPresetDefault
+	}
+	return a.Preset
+}
+
+func (a Audit) GetLogPath() string {
+	if a.LogPath == "" {
+		return defaultAuditLogPath
+	}
+	return a.LogPath
+}
+
+// GetExtraArgs returns apiserver flags of audit logging.
+func (a Audit) GetExtraArgs() map[string]string {
+	args := map[string]string{
+		"audit-policy-file":   AuditPolicyFile,
+		"audit-log-path":      a.GetLogPath(),
+		"audit-log-maxage":    strconv.Itoa(withDefault(a.MaxAge, defaultAuditMaxAge)),
+		"audit-log-maxbackup": strconv.Itoa(withDefault(a.MaxBackup, defaultAuditBackup)),
+		"audit-log-maxsize":   strconv.Itoa(withDefault(a.MaxSize, defaultAuditMaxSize)),
+	}
+	if a.Webhook != nil {
+		args["audit-webhook-config-file"] = AuditWebhookFile
+		if a.Webhook.Mode != "" {
+			args["audit-webhook-mode"] = a.Webhook.Mode
+		}
+	}
+	return args
+}
+
+// GetExtraVolumes returns mounts of the policy and log directory to the apiserver.
+func (a Audit) GetExtraVolumes() []HostPathMount {
+	logDir := filepath.Dir(a.GetLogPath())
+	return []HostPathMount{
+		{Name: "audit-policy", HostPath: AuditDir, MountPath: AuditDir, ReadOnly: true, PathType: "DirectoryOrCreate"},
+		{Name: "audit-log", HostPath: logDir, MountPath: logDir, PathType: "DirectoryOrCreate"},
+	}
+}
+
+func withDefault(value, defaultValue int) int {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func (dc *DeploymentConfig) ValidateAudit() error {
+	a := dc.Kubernetes.Audit
+	if !a.Enabled {
+		return nil
+	}
+	if a.Policy != "" && a.Preset != "" {
+		return fmt.Errorf("audit policy and preset can not be used together")
+	}
+	if !contains(auditPresets, a.GetPreset()) {
+		return fmt.Errorf("unknown audit preset \"%s\", valid presets: %s", a.Preset, strings.Join(auditPresets, ", "))
+	}
+	if !filepath.IsAbs(a.GetLogPath()) {
+		return fmt.Errorf("audit log path must be absolute")
+	}
+	if a.MaxAge < 0 || a.MaxBackup < 0 || a.MaxSize < 0 {
+		return fmt.Errorf("audit maxAge, maxBackup and maxSize must be positive")
+	}
+	if a.Webhook != nil {
+		if strings.TrimSpace(a.Webhook.KubeConfig) == "" {
+			return fmt.Errorf("audit webhook requires kubeConfig")
+		}
+		if a.Webhook.Mode != "" && !contains(auditWebhookModes, a.Webhook.Mode) {
+			return fmt.Errorf("unknown audit webhook mode \"%s\", valid modes: %s", a.Webhook.Mode,
+				strings.Join(auditWebhookModes, ", "))
+		}
+	}
+	for arg := range dc.Kubernetes.ApiServer.ExtraArgs {
+		if strings.HasPrefix(arg, "audit-") {
+			return fmt.Errorf("apiserver arg \"%s\" can not be used with kubernetes.audit", arg)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	CalicoModeManifest            = "manifest"
+	CalicoModeOperator            = "operator"
+	CalicoEncapsulationIPIP       = "IPIP"
+	CalicoEncapsulationIPIPCross  = "IPIPCrossSubnet"
+	CalicoEncapsulationVXLAN      = "VXLAN"
+	CalicoEncapsulationVXLANCross = "VXLANCrossSubnet"
+	CalicoEncapsulationNone       = "None"
+)
+
+var (
+	calicoModes          = []string{CalicoModeManifest, CalicoModeOperator}
+	calicoEncapsulations = []string{CalicoEncapsulationIPIP, CalicoEncapsulationIPIPCross, CalicoEncapsulationVXLAN,
+		CalicoEncapsulationVXLANCross, CalicoEncapsulationNone}
+)
+
+func (c Calico) GetMode() string {
+	if c.Mode == "" {
+		return CalicoModeManifest
+	}
+	return c.Mode
+}
+
+func (c Calico) GetEncapsulation() string {
+	if c.Encapsulation == "" {
+		return CalicoEncapsulationIPIP
+	}
+	return c.Encapsulation
+}
+
+// GetIPv6Encapsulation returns encapsulation of the IPv6 pool, calico encapsulates IPv6 only with VXLAN.
+func (c Calico) GetIPv6Encapsulation() string {
+	switch c.GetEncapsulation() {
+	case CalicoEncapsulationVXLAN, CalicoEncapsulationVXLANCross:
+		return c.GetEncapsulation()
+	}
+	return CalicoEncapsulationNone
+}
+
+// GetBackend returns the calico networking backend, BGP is not needed when all traffic is in VXLAN.
+func (c Calico) GetBackend() string {
+	if c.GetEncapsulation() == CalicoEncapsulationVXLAN {
+		return "vxlan"
+	}
+	return "bird"
+}
+
+// GetCalicoAutodetectionRegex returns a regex matching only iface.
+func GetCalicoAutodetectionRegex(iface string) string {
+	return fmt.Sprintf("^%s$", regexp.QuoteMeta(iface))
+}
+
+// GetCalicoAutodetectionEnvVars returns calico-node variables detecting node addresses on iface.
+func (dc *DeploymentConfig) GetCalicoAutodetectionEnvVars(iface string) []string {
+	method := fmt.Sprintf("interface=%s", GetCalicoAutodetectionRegex(iface))
+	envVars := []string{fmt.Sprintf("IP_AUTODETECTION_METHOD=%s", method)}
+	if dc.IPv6Enabled() {
+		envVars = append(envVars, fmt.Sprintf("IP6_AUTODETECTION_METHOD=%s", method))
+	}
+	return envVars
+}
+
+// GetCalicoNetworkEnvVars returns calico-node variables of the pools and encapsulation, they are applied before the
+// ones in deployment config.
+func (dc *DeploymentConfig) GetCalicoNetworkEnvVars() []string {
+	calico := dc.Kubernetes.Calico
+	var envVars []string
+	if cidr := dc.GetPodCIDR(false); cidr != "" {
+		envVars = append(envVars, fmt.Sprintf("CALICO_IPV4POOL_CIDR=%s", cidr))
+	}
+	envVars = append(envVars,
+		fmt.Sprintf("CALICO_IPV4POOL_IPIP=%s", poolEncapsulationMode(calico.GetEncapsulation(), "IPIP")),
+		fmt.Sprintf("CALICO_IPV4POOL_VXLAN=%s", poolEncapsulationMode(calico.GetEncapsulation(), "VXLAN")))
+	if !dc.IPv6Enabled() {
+		return envVars
+	}
+	envVars = append(envVars,
+		"IP6=autodetect",
+		"FELIX_IPV6SUPPORT=true",
+		fmt.Sprintf("CALICO_IPV6POOL_CIDR=%s", dc.GetPodCIDR(true)),
+		"CALICO_IPV6POOL_NAT_OUTGOING=true",
+		fmt.Sprintf("CALICO_IPV6POOL_VXLAN=%s", poolEncapsulationMode(calico.GetIPv6Encapsulation(), "VXLAN")))
+	if !dc.DualStack() {
+		// router id of BGP is taken from the IPv4 address otherwise
+		envVars = append(envVars, "IP=none", "CALICO_ROUTER_ID=hash")
+	}
+	return envVars
+}
+
+// poolEncapsulationMode returns the value of the pool variable of the encapsulation type, Always, CrossSubnet or
+// Never.
+func poolEncapsulationMode(encapsulation, encapsulationType string) string {
+	switch encapsulation {
+	case encapsulationType:
+		return "Always"
+	case encapsulationType + "CrossSubnet":
+		return "CrossSubnet"
+	}
+	return "Never"
+}
+
+func (dc *DeploymentConfig) validateCalico() error {
+	calico := dc.Kubernetes.Calico
+	if !contains(calicoModes, calico.GetMode()) {
+		return fmt.Errorf("unknown calico mode \"%s\", valid modes: %s", calico.Mode, strings.Join(calicoModes, ", "))
+	}
+	if !contains(calicoEncapsulations, calico.GetEncapsulation()) {
+		return fmt.Errorf("unknown calico encapsulation \"%s\", valid encapsulations: %s", calico.Encapsulation,
+			strings.Join(calicoEncapsulations, ", "))
+	}
+	if calico.MTU < 0 {
+		return fmt.Errorf("calico mtu must be positive")
+	}
+	if calico.GetMode() == CalicoModeOperator && len(calico.EnvVars) > 0 {
+		return fmt.Errorf("calico envVars can not be used in operator mode")
+	}
+	if calico.GetMode() == CalicoModeOperator && len(dc.GetNodeInterfaces()) > 0 && dc.GetSharedNodeInterface() == "" {
+		return fmt.Errorf("calico operator mode requires the same interface on all nodes")
+	}
+	if calico.Encapsulation != "" && calico.GetIPv6Encapsulation() != calico.Encapsulation &&
+		dc.GetPodCIDR(false) == "" {
+		return fmt.Errorf("calico encapsulation \"%s\" is not supported for IPv6 pools", calico.Encapsulation)
+	}
+	for _, env := range calico.EnvVars {
+		if !strings.Contains(env, "=") {
+			return fmt.Errorf("calico env var \"%s\" must be in NAME=value format", env)
+		}
+	}
+	return nil
+}
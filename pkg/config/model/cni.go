@@ -0,0 +1,156 @@
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	CNIPluginCalico       = "calico"
+	CNIPluginFlannel      = "flannel"
+	CNIPluginNone         = "none"
+	defaultFlannelUrl     = "https://github.com/flannel-io/flannel/releases/download/v{version}/kube-flannel.yml"
+	defaultFlannelBackend = "vxlan"
+	// NetworkInterfaceLabel is set by kubelet to the interface of the node, network plugin daemonsets are copied for
+	// each interface and select their nodes with it
+	NetworkInterfaceLabel = "tkube.io/network-interface"
+)
+
+var (
+	cniPlugins      = []string{CNIPluginCalico, CNIPluginFlannel, CNIPluginNone}
+	flannelBackends = []string{"vxlan", "host-gw", "wireguard"}
+	// interfaces are used as label values
+	interfaceRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_.-]{0,61}[A-Za-z0-9])?$`)
+)
+
+type CNI struct {
+	// Plugin is calico, flannel or none, calico is installed if it is empty
+	Plugin  string  `yaml:"plugin,omitempty"`
+	Flannel Flannel `yaml:"flannel,omitempty"`
+	// Manifests are local paths of manifests applied in order with none plugin
+	Manifests []string `yaml:"manifests,omitempty"`
+	// Helm is a local chart installed after the manifests with none plugin
+	Helm *CNIHelm `yaml:"helm,omitempty"`
+}
+
+type Flannel struct {
+	// Url of the manifest, {version} is replaced with the flannel version
+	Url     string `yaml:"url,omitempty"`
+	Backend string `yaml:"backend,omitempty"`
+}
+
+// CNIHelm installs a network plugin from a packaged chart on the machine running tkube.
+type CNIHelm struct {
+	Chart     string `yaml:"chart"`
+	Release   string `yaml:"release"`
+	Namespace string `yaml:"namespace,omitempty"`
+	// Values are local paths of value files
+	Values []string `yaml:"values,omitempty"`
+}
+
+func (h CNIHelm) GetNamespace() string {
+	if h.Namespace == "" {
+		return "kube-system"
+	}
+	return h.Namespace
+}
+
+// HasHooks reports whether a network plugin is installed from user supplied manifests or chart.
+func (c CNI) HasHooks() bool {
+	return len(c.Manifests) > 0 || c.Helm != nil
+}
+
+func (c CNI) GetPlugin() string {
+	if c.Plugin == "" {
+		return CNIPluginCalico
+	}
+	return c.Plugin
+}
+
+func (f Flannel) GetBackend() string {
+	if f.Backend == "" {
+		return defaultFlannelBackend
+	}
+	return f.Backend
+}
+
+func (dc *DeploymentConfig) GetFlannelExactUrl(flannelVersion string) string {
+	url := dc.Kubernetes.CNI.Flannel.Url
+	if url == "" {
+		url = defaultFlannelUrl
+	}
+	return strings.ReplaceAll(url, "{version}", flannelVersion)
+}
+
+// GetNodeInterfaces returns the distinct interfaces of the nodes in node order, empty if the network plugin does not
+// use them.
+func (dc *DeploymentConfig) GetNodeInterfaces() []string {
+	if dc.Kubernetes.CNI.GetPlugin() == CNIPluginNone {
+		return nil
+	}
+	var interfaces []string
+	for _, node := range dc.Nodes {
+		if node.Interface != "" && !contains(interfaces, node.Interface) {
+			interfaces = append(interfaces, node.Interface)
+		}
+	}
+	return interfaces
+}
+
+// GetSharedNodeInterface returns the interface of the nodes if all of them have the same one. Network plugins are
+// pinned to it directly, instead of running a copy for each interface.
+func (dc *DeploymentConfig) GetSharedNodeInterface() string {
+	interfaces := dc.GetNodeInterfaces()
+	if len(interfaces) != 1 {
+		return ""
+	}
+	for _, node := range dc.Nodes {
+		if node.Interface == "" {
+			return ""
+		}
+	}
+	return interfaces[0]
+}
+
+func (dc *DeploymentConfig) ValidateCNI() error {
+	cni := dc.Kubernetes.CNI
+	if !contains(cniPlugins, cni.GetPlugin()) {
+		return fmt.Errorf("unknown cni plugin \"%s\", valid plugins: %s", cni.Plugin, strings.Join(cniPlugins, ", "))
+	}
+	if cni.GetPlugin() != CNIPluginNone && cni.HasHooks() {
+		return fmt.Errorf("cni manifests and helm require none cni plugin")
+	}
+	if cni.Helm != nil {
+		if !strings.HasSuffix(cni.Helm.Chart, ".tgz") {
+			return fmt.Errorf("cni helm chart must be the path of a packaged chart (.tgz)")
+		}
+		if cni.Helm.Release == "" {
+			return fmt.Errorf("cni helm requires release")
+		}
+	}
+	for _, iface := range dc.GetNodeInterfaces() {
+		if !interfaceRegex.MatchString(iface) {
+			return fmt.Errorf("interface \"%s\" can not be used by the network plugin", iface)
+		}
+	}
+	if cni.GetPlugin() == CNIPluginCalico {
+		if err := dc.validateCalico(); err != nil {
+			return err
+		}
+	}
+	if cni.GetPlugin() != CNIPluginFlannel {
+		if cni.Flannel != (Flannel{}) {
+			return fmt.Errorf("flannel settings require flannel cni plugin")
+		}
+		return nil
+	}
+	if !contains(flannelBackends, cni.Flannel.GetBackend()) {
+		return fmt.Errorf("unknown flannel backend \"%s\", valid backends: %s", cni.Flannel.Backend,
+			strings.Join(flannelBackends, ", "))
+	}
+	if len(SplitCIDRs(dc.Kubernetes.PodSubnet)) == 0 {
+		return fmt.Errorf("flannel requires kubernetes.podSubnet")
+	}
+	return nil
+}
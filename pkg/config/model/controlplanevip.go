@@ -0,0 +1,126 @@
+package model
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	VIPProviderKeepalived = "keepalived"
+	VIPProviderKubeVip    = "kube-vip"
+	KubeVipModeARP        = "arp"
+	KubeVipModeBGP        = "bgp"
+	DefaultKubeVipImage   = "ghcr.io/kube-vip/kube-vip"
+	DefaultKubeVipVersion = "v0.8.9"
+)
+
+// ControlPlaneVIP selects how the virtual IP of the control plane is floated between masters. The keepalived
+// provider is configured in the keepalived section.
+type ControlPlaneVIP struct {
+	Provider string `yaml:"provider,omitempty"`
+	// Address defaults to keepalived.virtualIP
+	Address net.IP  `yaml:"address,omitempty"`
+	KubeVip KubeVip `yaml:"kubeVip,omitempty"`
+}
+
+type KubeVip struct {
+	Image   string     `yaml:"image,omitempty"`
+	Version string     `yaml:"version,omitempty"`
+	Mode    string     `yaml:"mode,omitempty"`
+	BGP     KubeVipBGP `yaml:"bgp,omitempty"`
+}
+
+type KubeVipBGP struct {
+	AS       int    `yaml:"as,omitempty"`
+	PeerAS   int    `yaml:"peerAS,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Peers are addresses of BGP routers, each one uses PeerAS
+	Peers []string `yaml:"peers,omitempty"`
+}
+
+func (dc *DeploymentConfig) GetVIPProvider() string {
+	if dc.ControlPlaneVIP.Provider == "" {
+		return VIPProviderKeepalived
+	}
+	return dc.ControlPlaneVIP.Provider
+}
+
+// KeepalivedEnabled reports whether keepalived floats the control plane virtual IP.
+func (dc *DeploymentConfig) KeepalivedEnabled() bool {
+	return dc.GetVIPProvider() == VIPProviderKeepalived && dc.Keepalived.Enabled
+}
+
+func (dc *DeploymentConfig) KubeVipEnabled() bool {
+	return dc.GetVIPProvider() == VIPProviderKubeVip
+}
+
+// VIPEnabled reports whether the control plane is reached from a virtual IP.
+func (dc *DeploymentConfig) VIPEnabled() bool {
+	return dc.KeepalivedEnabled() || dc.KubeVipEnabled()
+}
+
+func (dc *DeploymentConfig) GetVirtualIP() net.IP {
+	if dc.ControlPlaneVIP.Address != nil {
+		return dc.ControlPlaneVIP.Address
+	}
+	return dc.Keepalived.VirtualIP
+}
+
+func (kv KubeVip) GetImage() string {
+	image := kv.Image
+	if image == "" {
+		image = DefaultKubeVipImage
+	}
+	v := kv.Version
+	if v == "" {
+		v = DefaultKubeVipVersion
+	}
+	return fmt.Sprintf("%s:%s", image, v)
+}
+
+func (kv KubeVip) GetMode() string {
+	if kv.Mode == "" {
+		return KubeVipModeARP
+	}
+	return kv.Mode
+}
+
+func (dc *DeploymentConfig) ValidateControlPlaneVIP() error {
+	switch dc.GetVIPProvider() {
+	case VIPProviderKeepalived:
+		return nil
+	case VIPProviderKubeVip:
+	default:
+		return fmt.Errorf("unknown control plane VIP provider \"%s\", valid providers: %s, %s",
+			dc.ControlPlaneVIP.Provider, VIPProviderKeepalived, VIPProviderKubeVip)
+	}
+	if dc.Keepalived.Enabled {
+		return fmt.Errorf("keepalived must be disabled with control plane VIP provider \"%s\"", VIPProviderKubeVip)
+	}
+	if dc.GetVirtualIP() == nil || dc.GetVirtualIP().IsLoopback() {
+		return fmt.Errorf("controlPlaneVIP.address is required with control plane VIP provider \"%s\"",
+			VIPProviderKubeVip)
+	}
+	kv := dc.ControlPlaneVIP.KubeVip
+	switch kv.GetMode() {
+	case KubeVipModeARP:
+	case KubeVipModeBGP:
+		if kv.BGP.AS == 0 || kv.BGP.PeerAS == 0 || len(kv.BGP.Peers) == 0 {
+			return fmt.Errorf("kube-vip bgp mode requires as, peerAS and peers")
+		}
+		for _, peer := range kv.BGP.Peers {
+			if net.ParseIP(peer) == nil {
+				return fmt.Errorf("kube-vip bgp peer \"%s\" is not an IP address", peer)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown kube-vip mode \"%s\", valid modes: %s, %s", kv.Mode, KubeVipModeARP,
+			KubeVipModeBGP)
+	}
+	for _, node := range dc.GetMasterKubeNodes() {
+		if kv.GetMode() == KubeVipModeARP && node.Interface == "" {
+			return fmt.Errorf("interface of \"%s\" is required for kube-vip arp mode", node.Hostname)
+		}
+	}
+	return nil
+}
@@ -3,26 +3,30 @@ package model
 import (
 	"encoding/json"
 	"net"
-	"reflect"
 	"strings"
 
 	"github.com/hashicorp/go-version"
-	"github.com/mitchellh/mapstructure"
-	"github.com/spf13/viper"
 )
 
 type DeploymentConfig struct {
-	Nodes       []KubeNode `yaml:"nodes"`
-	CentOS      CentOS     `yaml:"centOS,omitempty"`
-	Packages    []string   `yaml:"packages"`
-	Keepalived  KeepAliveD `yaml:"keepalived"`
-	Containerd  ContainerD `yaml:"containerd"`
-	Docker      Docker     `yaml:"docker"`
-	Etcd        Etcd       `yaml:"etcd"`
-	Kubernetes  Kubernetes `yaml:"kubernetes"`
-	Helm        Helm       `yaml:"helm"`
-	Helmfile    Helmfile   `yaml:"helmfile"`
-	CustomRepos []Repo     `yaml:"customRepos"`
+	ApiVersion      string                  `yaml:"apiVersion"`
+	Kind            string                  `yaml:"kind"`
+	Versions        Versions                `yaml:"versions,omitempty"`
+	Nodes           []KubeNode              `yaml:"nodes"`
+	Roles           map[string]NodeSettings `yaml:"roles,omitempty"`
+	NodeGroups      map[string]NodeSettings `yaml:"nodeGroups,omitempty"`
+	CentOS          CentOS                  `yaml:"centOS,omitempty"`
+	Packages        []string                `yaml:"packages"`
+	Keepalived      KeepAliveD              `yaml:"keepalived"`
+	ControlPlaneVIP ControlPlaneVIP         `yaml:"controlPlaneVIP,omitempty"`
+	LoadBalancer    LoadBalancer            `yaml:"loadBalancer,omitempty"`
+	Containerd      ContainerD              `yaml:"containerd"`
+	Docker          Docker                  `yaml:"docker"`
+	Etcd            Etcd                    `yaml:"etcd"`
+	Kubernetes      Kubernetes              `yaml:"kubernetes"`
+	Helm            Helm                    `yaml:"helm"`
+	Helmfile        Helmfile                `yaml:"helmfile"`
+	CustomRepos     []Repo                  `yaml:"customRepos"`
 }
 
 type KubeNodes struct {
@@ -37,20 +41,30 @@ type KubeNode struct {
 	SshUser           string `yaml:"sshUser"`
 	SshPass           string `yaml:"sshPass"`
 	SshPrivateKeyPath string `yaml:"sshPrivateKeyPath"`
+	Group             string `yaml:"group,omitempty"`
+	NodeIP            net.IP `yaml:"nodeIP,omitempty"`
+	// IPv6 is the second address of the node in dual-stack clusters
+	IPv6         net.IP `yaml:"ipv6,omitempty"`
+	NodeSettings `yaml:",inline"`
+	Keepalived   *KeepalivedNode `yaml:"keepalived,omitempty"`
+}
+
+// Versions are the component versions the cluster is installed with.
+type Versions struct {
+	Kubernetes string `yaml:"kubernetes,omitempty"`
+	Docker     string `yaml:"docker,omitempty"`
+	Containerd string `yaml:"containerd,omitempty"`
+	Etcd       string `yaml:"etcd,omitempty"`
+	Calico     string `yaml:"calico,omitempty"`
+	Flannel    string `yaml:"flannel,omitempty"`
+	Helm       string `yaml:"helm,omitempty"`
+	Helmfile   string `yaml:"helmfile,omitempty"`
 }
 
 type CentOS struct {
 	SetSelinuxPermissive bool `yaml:"setSelinuxPermissive"`
 }
 
-type KeepAliveD struct {
-	Enabled         bool   `yaml:"enabled"`
-	VirtualIP       net.IP `yaml:"virtualIP"`
-	VirtualRouterId int    `yaml:"virtualRouterId"`
-	Priority        int    `yaml:"priority"`
-	AuthPass        string `json:"authPass"`
-}
-
 type ContainerD struct {
 	Cri CRI `yaml:"cri"`
 }
@@ -92,12 +106,32 @@ type Kubernetes struct {
 	PodSubnet             string `yaml:"podSubnet"`
 	SchedulePodsOnMasters bool   `yaml:"schedulePodsOnMasters"`
 	Calico                Calico `yaml:"calico"`
-	CalicoUrl             string `yaml:"calicoUrl,omitempty"` // deprecated
+	CNI                   CNI    `yaml:"cni,omitempty"`
+	// ControlPlaneEndpoint is a DNS name or IP with optional port of a load balancer outside the cluster
+	ControlPlaneEndpoint string                `yaml:"controlPlaneEndpoint,omitempty"`
+	ServiceSubnet        string                `yaml:"serviceSubnet,omitempty"`
+	DNSDomain            string                `yaml:"dnsDomain,omitempty"`
+	ClusterName          string                `yaml:"clusterName,omitempty"`
+	CertSANs             []string              `yaml:"certSANs,omitempty"`
+	ApiServer            ControlPlaneComponent `yaml:"apiServer,omitempty"`
+	ControllerManager    ControlPlaneComponent `yaml:"controllerManager,omitempty"`
+	Scheduler            ControlPlaneComponent `yaml:"scheduler,omitempty"`
+	Patches              []Patch               `yaml:"patches,omitempty"`
+	Kubelet              *Kubelet              `yaml:"kubelet,omitempty"`
+	KubeProxy            KubeProxy             `yaml:"kubeProxy,omitempty"`
+	Audit                Audit                 `yaml:"audit,omitempty"`
+	EncryptionAtRest     EncryptionAtRest      `yaml:"encryptionAtRest,omitempty"`
 }
 
 type Calico struct {
+	// Url of the manifest, or of the operator manifest in operator mode
 	Url     string   `yaml:"url"`
 	EnvVars []string `yaml:"envVars"`
+	// Mode is manifest or operator, calico is installed from its manifest if it is empty
+	Mode string `yaml:"mode,omitempty"`
+	// Encapsulation of pod traffic, IPIP if it is empty
+	Encapsulation string `yaml:"encapsulation,omitempty"`
+	MTU           int    `yaml:"mtu,omitempty"`
 }
 
 type Helm struct {
@@ -119,20 +153,6 @@ func (repo Repo) ShortName() string {
 	return strings.ReplaceAll(strings.ToLower(repo.Name), " ", "")
 }
 
-func DeploymentCfgViperDecodeHook() viper.DecoderConfigOption {
-	return viper.DecodeHook(
-		mapstructure.ComposeDecodeHookFunc(
-			func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
-				if f.Kind() != reflect.String || t != reflect.TypeOf(net.IP{}) {
-					return data, nil
-				}
-				ip := net.ParseIP(data.(string))
-				return ip, nil
-			},
-		),
-	)
-}
-
 func (dc *DeploymentConfig) GetKubeNodes() []KubeNode {
 	return dc.Nodes
 }
@@ -190,6 +210,15 @@ func (nodes *KubeNodes) GetWorkerKubeNodes() []KubeNode {
 	return workerNodes
 }
 
+func (nodes *KubeNodes) Contains(ip net.IP) bool {
+	for _, node := range nodes.Nodes {
+		if node.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (nodes *KubeNodes) IncludeMaster() bool {
 	for _, node := range nodes.Nodes {
 		if node.KubeType == "master" {
@@ -248,13 +277,17 @@ func (dc *DeploymentConfig) GetHelmfileExactUrl(helmfileVersion string) (exactUr
 
 func (dc *DeploymentConfig) GetCalicoExactUrl(calicoVersion string) (exactUrl string) {
 	if dc.Kubernetes.Calico.Url == "default" {
+		manifest := "calico.yaml"
+		if dc.Kubernetes.Calico.GetMode() == CalicoModeOperator {
+			manifest = "tigera-operator.yaml"
+		}
 		var url string
 		calicoSemVer, _ := version.NewVersion(calicoVersion)
 		calicoArchivedSemVer, _ := version.NewVersion("3.23")
 		if calicoSemVer.GreaterThan(calicoArchivedSemVer) {
-			url = "https://raw.githubusercontent.com/projectcalico/calico/v{version}/manifests/calico.yaml"
+			url = "https://raw.githubusercontent.com/projectcalico/calico/v{version}/manifests/" + manifest
 		} else {
-			url = "https://projectcalico.docs.tigera.io/archive/v{version}/manifests/calico.yaml"
+			url = "https://projectcalico.docs.tigera.io/archive/v{version}/manifests/" + manifest
 		}
 		return strings.ReplaceAll(url, "{version}", calicoVersion)
 	} else {
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	EncryptionProviderAESCBC    = "aescbc"
+	EncryptionProviderSecretbox = "secretbox"
+	EncryptionDir               = "/etc/kubernetes/encryption"
+	EncryptionConfigFile        = EncryptionDir + "/config.yaml"
+)
+
+var encryptionProviders = []string{EncryptionProviderAESCBC, EncryptionProviderSecretbox}
+
+// EncryptionAtRest encrypts secrets in etcd. Keys are generated by tkube and kept only on masters.
+type EncryptionAtRest struct {
+	Enabled bool `yaml:"enabled"`
+	// Provider is aescbc or secretbox, new keys are created with it
+	Provider string `yaml:"provider,omitempty"`
+}
+
+func (e EncryptionAtRest) GetProvider() string {
+	if e.Provider == "" {
+		return EncryptionProviderAESCBC
+	}
+	return e.Provider
+}
+
+func (e EncryptionAtRest) GetExtraArgs() map[string]string {
+	return map[string]string{"encryption-provider-config": EncryptionConfigFile}
+}
+
+func (e EncryptionAtRest) GetExtraVolumes() []HostPathMount {
+	return []HostPathMount{
+		{Name: "encryption-config", HostPath: EncryptionDir, MountPath: EncryptionDir, ReadOnly: true,
+			PathType: "DirectoryOrCreate"},
+	}
+}
+
+func (dc *DeploymentConfig) ValidateEncryptionAtRest() error {
+	e := dc.Kubernetes.EncryptionAtRest
+	if !e.Enabled {
+		return nil
+	}
+	if !contains(encryptionProviders, e.GetProvider()) {
+		return fmt.Errorf("unknown encryption provider \"%s\", valid providers: %s", e.Provider,
+			strings.Join(encryptionProviders, ", "))
+	}
+	if _, ok := dc.Kubernetes.ApiServer.ExtraArgs["encryption-provider-config"]; ok {
+		return fmt.Errorf("apiserver arg \"encryption-provider-config\" can not be used with " +
+			"kubernetes.encryptionAtRest")
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"com.github.tunahansezen/tkube/pkg/constant"
+)
+
+// ExternalEndpointEnabled reports whether the control plane is fronted by a load balancer outside the cluster.
+func (dc *DeploymentConfig) ExternalEndpointEnabled() bool {
+	return dc.Kubernetes.ControlPlaneEndpoint != ""
+}
+
+// GetControlPlaneEndpoint returns host:port of the control plane. It is the external endpoint if defined, otherwise
+// the virtual IP or the first master.
+func (dc *DeploymentConfig) GetControlPlaneEndpoint(firstMasterIP net.IP) string {
+	if dc.ExternalEndpointEnabled() {
+		host, port := dc.getExternalEndpoint()
+		return net.JoinHostPort(host, port)
+	}
+	ip := firstMasterIP
+	if dc.VIPEnabled() {
+		ip = dc.GetVirtualIP()
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(dc.GetControlPlanePort()))
+}
+
+// GetControlPlaneEndpointHost returns the DNS name or IP of the external endpoint.
+func (dc *DeploymentConfig) GetControlPlaneEndpointHost() string {
+	host, _ := dc.getExternalEndpoint()
+	return host
+}
+
+func (dc *DeploymentConfig) getExternalEndpoint() (host string, port string) {
+	host, port, err := net.SplitHostPort(dc.Kubernetes.ControlPlaneEndpoint)
+	if err != nil { // port is optional
+		return dc.Kubernetes.ControlPlaneEndpoint, strconv.Itoa(constant.ApiServerPort)
+	}
+	return host, port
+}
+
+func (dc *DeploymentConfig) ValidateControlPlaneEndpoint() error {
+	if !dc.ExternalEndpointEnabled() {
+		return nil
+	}
+	if dc.VIPEnabled() || dc.LoadBalancer.Type != "" {
+		return fmt.Errorf("kubernetes.controlPlaneEndpoint can not be used with a control plane virtual IP or " +
+			"load balancer")
+	}
+	host, port := dc.getExternalEndpoint()
+	if host == "" {
+		return fmt.Errorf("invalid kubernetes.controlPlaneEndpoint \"%s\"", dc.Kubernetes.ControlPlaneEndpoint)
+	}
+	if p, err := strconv.Atoi(port); err != nil || p < 1 || p > 65535 {
+		return fmt.Errorf("invalid port \"%s\" in kubernetes.controlPlaneEndpoint", port)
+	}
+	return nil
+}
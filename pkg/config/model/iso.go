@@ -4,7 +4,9 @@ type IsoVersions struct {
 	Kubernetes string `yaml:"kubernetes"`
 	Docker     string `yaml:"docker"`
 	Calico     string `yaml:"calico"`
+	Flannel    string `yaml:"flannel,omitempty"`
 	Etcd       string `yaml:"etcd"`
 	Helm       string `yaml:"helm"`
 	Helmfile   string `yaml:"helmfile"`
+	KubeVip    string `yaml:"kubeVip"`
 }
@@ -0,0 +1,138 @@
+package model
+
+import (
+	"fmt"
+	"net"
+)
+
+const (
+	KeepalivedStateMaster = "MASTER"
+	KeepalivedStateBackup = "BACKUP"
+	defaultCheckInterval  = 3
+	defaultCheckFall      = 10
+	defaultCheckRise      = 2
+	defaultCheckWeight    = -2
+)
+
+type KeepAliveD struct {
+	Enabled         bool   `yaml:"enabled"`
+	VirtualIP       net.IP `yaml:"virtualIP"`
+	VirtualRouterId int    `yaml:"virtualRouterId"`
+	Priority        int    `yaml:"priority"`
+	AuthPass        string `yaml:"authPass"`
+	// Unicast sends VRRP adverts to the other masters instead of multicast
+	Unicast   bool             `yaml:"unicast,omitempty"`
+	NoPreempt bool             `yaml:"nopreempt,omitempty"`
+	Check     KeepalivedCheck  `yaml:"check,omitempty"`
+	Notify    KeepalivedNotify `yaml:"notify,omitempty"`
+}
+
+// KeepalivedCheck is the vrrp_script checking the apiserver, unset values fall back to defaults. Weight is a pointer
+// since 0 is a valid weight, a failing check does not change the priority then.
+type KeepalivedCheck struct {
+	Interval int  `yaml:"interval,omitempty"`
+	Fall     int  `yaml:"fall,omitempty"`
+	Rise     int  `yaml:"rise,omitempty"`
+	Weight   *int `yaml:"weight,omitempty"`
+}
+
+// KeepalivedNotify holds paths of scripts on masters which are called on state changes.
+type KeepalivedNotify struct {
+	Master string `yaml:"master,omitempty"`
+	Backup string `yaml:"backup,omitempty"`
+	Fault  string `yaml:"fault,omitempty"`
+	Stop   string `yaml:"stop,omitempty"`
+	Notify string `yaml:"notify,omitempty"`
+}
+
+type KeepalivedNode struct {
+	Priority int    `yaml:"priority,omitempty"`
+	State    string `yaml:"state,omitempty"`
+}
+
+// GetKeepalivedNode returns the VRRP priority and state of a master. Unless they are set on the node, the first master
+// starts as MASTER with keepalived.priority and the others as BACKUP with decreasing priorities. All masters start as
+// BACKUP with nopreempt, since keepalived ignores nopreempt otherwise.
+func (dc *DeploymentConfig) GetKeepalivedNode(node KubeNode) KeepalivedNode {
+	index := 0
+	for i, master := range dc.GetMasterKubeNodes() {
+		if master.IP.Equal(node.IP) {
+			index = i
+			break
+		}
+	}
+	var kn KeepalivedNode
+	if node.Keepalived != nil {
+		kn = *node.Keepalived
+	}
+	if kn.Priority == 0 {
+		kn.Priority = max(dc.Keepalived.Priority-index, 1)
+	}
+	if kn.State == "" {
+		kn.State = KeepalivedStateBackup
+		if index == 0 && !dc.Keepalived.NoPreempt {
+			kn.State = KeepalivedStateMaster
+		}
+	}
+	return kn
+}
+
+// GetKeepalivedPeers returns IPs of the other masters for unicast, in the family of the virtual IP.
+func (dc *DeploymentConfig) GetKeepalivedPeers(node KubeNode) []net.IP {
+	var peers []net.IP
+	for _, master := range dc.GetMasterKubeNodes() {
+		if !master.IP.Equal(node.IP) {
+			peers = append(peers, master.AddressOfFamily(dc.Keepalived.VirtualIP))
+		}
+	}
+	return peers
+}
+
+func (kc KeepalivedCheck) WithDefaults() KeepalivedCheck {
+	if kc.Interval == 0 {
+		kc.Interval = defaultCheckInterval
+	}
+	if kc.Fall == 0 {
+		kc.Fall = defaultCheckFall
+	}
+	if kc.Rise == 0 {
+		kc.Rise = defaultCheckRise
+	}
+	if kc.Weight == nil {
+		weight := defaultCheckWeight
+		kc.Weight = &weight
+	}
+	return kc
+}
+
+func (dc *DeploymentConfig) ValidateKeepalived() error {
+	if !dc.KeepalivedEnabled() {
+		return nil
+	}
+	check := dc.Keepalived.Check
+	if check.Interval < 0 || check.Fall < 0 || check.Rise < 0 {
+		return fmt.Errorf("keepalived check interval, fall and rise must be positive")
+	}
+	if check.Weight != nil && (*check.Weight < -253 || *check.Weight > 253) {
+		return fmt.Errorf("keepalived check weight must be between -253 and 253")
+	}
+	for _, node := range dc.GetMasterKubeNodes() {
+		if dc.Keepalived.Unicast && node.AddressOfFamily(dc.Keepalived.VirtualIP) == nil {
+			return fmt.Errorf("\"%s\" has no address in the family of keepalived virtual IP for unicast",
+				node.Hostname)
+		}
+		kn := dc.GetKeepalivedNode(node)
+		if kn.Priority < 1 || kn.Priority > 254 {
+			return fmt.Errorf("keepalived priority of \"%s\" must be between 1 and 254", node.Hostname)
+		}
+		if kn.State != KeepalivedStateMaster && kn.State != KeepalivedStateBackup {
+			return fmt.Errorf("keepalived state of \"%s\" must be %s or %s", node.Hostname, KeepalivedStateMaster,
+				KeepalivedStateBackup)
+		}
+		if dc.Keepalived.NoPreempt && kn.State == KeepalivedStateMaster {
+			return fmt.Errorf("keepalived state of \"%s\" must be %s with nopreempt", node.Hostname,
+				KeepalivedStateBackup)
+		}
+	}
+	return nil
+}
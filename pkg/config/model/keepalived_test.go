@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestKeepalivedCheckWithDefaults(t *testing.T) {
+	tests := []struct {
+		check    KeepalivedCheck
+		expected int
+	}{
+		{KeepalivedCheck{}, defaultCheckWeight},
+		{KeepalivedCheck{Weight: intPtr(0)}, 0},
+		{KeepalivedCheck{Weight: intPtr(10)}, 10},
+	}
+	for _, test := range tests {
+		if weight := *test.check.WithDefaults().Weight; weight != test.expected {
+			t.Errorf("expected weight %d, got %d", test.expected, weight)
+		}
+	}
+	if check := (KeepalivedCheck{}).WithDefaults(); check.Interval != defaultCheckInterval ||
+		check.Fall != defaultCheckFall || check.Rise != defaultCheckRise {
+		t.Errorf("expected default interval, fall and rise, got %+v", check)
+	}
+}
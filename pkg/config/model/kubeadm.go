@@ -0,0 +1,155 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+)
+
+var (
+	// PatchTargets are the components kubeadm applies patches to
+	PatchTargets = []string{"etcd", "kube-apiserver", "kube-controller-manager", "kube-scheduler",
+		"kubeletconfiguration"}
+	patchTypes = []string{"strategic", "merge", "json"}
+)
+
+// ControlPlaneComponent holds settings of apiserver, controller manager and scheduler static pods.
+type ControlPlaneComponent struct {
+	ExtraArgs    map[string]string `yaml:"extraArgs,omitempty"`
+	ExtraVolumes []HostPathMount   `yaml:"extraVolumes,omitempty"`
+	FeatureGates map[string]bool   `yaml:"featureGates,omitempty"`
+}
+
+type HostPathMount struct {
+	Name      string `yaml:"name" json:"name"`
+	HostPath  string `yaml:"hostPath" json:"hostPath"`
+	MountPath string `yaml:"mountPath" json:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty" json:"readOnly,omitempty"`
+	PathType  string `yaml:"pathType,omitempty" json:"pathType,omitempty"`
+}
+
+// Patch is passed to kubeadm with --patches, it is applied to the manifests and configs kubeadm generates.
+type Patch struct {
+	Target string `yaml:"target"`
+	// Type is strategic (default), merge or json
+	Type  string `yaml:"type,omitempty"`
+	Patch string `yaml:"patch"`
+}
+
+func (p Patch) GetType() string {
+	if p.Type == "" {
+		return "strategic"
+	}
+	return p.Type
+}
+
+// GetExtraArgs returns extra args of the component with feature gates merged in.
+func (c ControlPlaneComponent) GetExtraArgs() map[string]string {
+	if len(c.ExtraArgs) == 0 && len(c.FeatureGates) == 0 {
+		return nil
+	}
+	args := make(map[string]string)
+	for k, v := range c.ExtraArgs {
+		args[k] = v
+	}
+	if len(c.FeatureGates) > 0 {
+		var gates []string
+		for _, gate := range SortedKeys(c.FeatureGates) {
+			gates = append(gates, fmt.Sprintf("%s=%t", gate, c.FeatureGates[gate]))
+		}
+		args["feature-gates"] = strings.Join(gates, ",")
+	}
+	return args
+}
+
+// GetApiServer returns apiserver settings with audit logging and encryption at rest wired in.
+func (dc *DeploymentConfig) GetApiServer() ControlPlaneComponent {
+	apiServer := dc.Kubernetes.ApiServer
+	var extraVolumes []HostPathMount
+	if audit := dc.Kubernetes.Audit; audit.Enabled {
+		apiServer.ExtraArgs = mergeMaps(apiServer.ExtraArgs, audit.GetExtraArgs())
+		extraVolumes = append(extraVolumes, audit.GetExtraVolumes()...)
+	}
+	if encryption := dc.Kubernetes.EncryptionAtRest; encryption.Enabled {
+		apiServer.ExtraArgs = mergeMaps(apiServer.ExtraArgs, encryption.GetExtraArgs())
+		extraVolumes = append(extraVolumes, encryption.GetExtraVolumes()...)
+	}
+	apiServer.ExtraVolumes = append(extraVolumes, apiServer.ExtraVolumes...)
+	return apiServer
+}
+
+func (c ControlPlaneComponent) validate(name string) error {
+	if _, ok := c.ExtraArgs["feature-gates"]; ok && len(c.FeatureGates) > 0 {
+		return fmt.Errorf("%s feature gates must be given either in extraArgs or featureGates", name)
+	}
+	for _, volume := range c.ExtraVolumes {
+		if volume.Name == "" || volume.HostPath == "" || volume.MountPath == "" {
+			return fmt.Errorf("%s extra volumes require name, hostPath and mountPath", name)
+		}
+	}
+	return nil
+}
+
+func (dc *DeploymentConfig) ValidateKubeadm() error {
+	k := dc.Kubernetes
+	if err := dc.validateNetworking(); err != nil {
+		return err
+	}
+	components := map[string]ControlPlaneComponent{
+		"apiServer":         k.ApiServer,
+		"controllerManager": k.ControllerManager,
+		"scheduler":         k.Scheduler,
+	}
+	for name, component := range components {
+		if err := component.validate(name); err != nil {
+			return err
+		}
+	}
+	return validatePatches(k.Patches)
+}
+
+// ValidateKubeadmVersion checks that kubeadm of kubeVersion can apply the patches of deployment config. Kubelet settings
+// of nodes are patches as well.
+func (dc *DeploymentConfig) ValidateKubeadmVersion(kubeVersion string) error {
+	kubeSemVer, err := version.NewVersion(kubeVersion)
+	if err != nil {
+		return err
+	}
+	kube119Ver, _ := version.NewVersion("1.19")
+	if !kubeSemVer.LessThan(kube119Ver) {
+		return nil
+	}
+	for _, node := range dc.Nodes {
+		settings := dc.GetNodeSettings(node)
+		if len(dc.Kubernetes.Patches) > 0 || len(settings.Patches) > 0 || settings.Kubelet != nil {
+			return fmt.Errorf("kubeadm patches of \"%s\" are supported since kubernetes 1.19", node.Hostname)
+		}
+	}
+	return nil
+}
+
+func validatePatches(patches []Patch) error {
+	for _, patch := range patches {
+		if !contains(PatchTargets, patch.Target) {
+			return fmt.Errorf("unknown patch target \"%s\", valid targets: %s", patch.Target,
+				strings.Join(PatchTargets, ", "))
+		}
+		if !contains(patchTypes, patch.GetType()) {
+			return fmt.Errorf("unknown patch type \"%s\", valid types: %s", patch.Type, strings.Join(patchTypes, ", "))
+		}
+		if strings.TrimSpace(patch.Patch) == "" {
+			return fmt.Errorf("patch of \"%s\" is empty", patch.Target)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestValidateKubeadmVersion(t *testing.T) {
+	patch := Patch{Target: "kube-apiserver", Patch: "metadata: {}"}
+	tests := []struct {
+		kubeVersion string
+		dc          DeploymentConfig
+		valid       bool
+	}{
+		{"1.18.20", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1"}}}, true},
+		{"1.18.20", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1"}},
+			Kubernetes: Kubernetes{Patches: []Patch{patch}}}, false},
+		{"1.18.20", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1", KubeType: "worker"}},
+			Roles: map[string]NodeSettings{"worker": {Kubelet: &Kubelet{MaxPods: 60}}}}, false},
+		{"1.19.16", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1",
+			NodeSettings: NodeSettings{Patches: []Patch{patch}}}}}, true},
+	}
+	for _, test := range tests {
+		if err := test.dc.ValidateKubeadmVersion(test.kubeVersion); (err == nil) != test.valid {
+			t.Errorf("ValidateKubeadmVersion(%s) of %+v = %v, expected valid: %t", test.kubeVersion, test.dc, err,
+				test.valid)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-version"
+)
+
+// Kubelet is rendered into the kubeadm KubeletConfiguration, so field names follow kubelet.config.k8s.io. Unset
+// fields keep kubelet defaults.
+type Kubelet struct {
+	MaxPods                     int               `yaml:"maxPods,omitempty"`
+	SystemReserved              map[string]string `yaml:"systemReserved,omitempty"`
+	KubeReserved                map[string]string `yaml:"kubeReserved,omitempty"`
+	EvictionHard                map[string]string `yaml:"evictionHard,omitempty"`
+	EvictionSoft                map[string]string `yaml:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod     map[string]string `yaml:"evictionSoftGracePeriod,omitempty"`
+	ImageGCHighThresholdPercent *int              `yaml:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent  *int              `yaml:"imageGCLowThresholdPercent,omitempty"`
+	SerializeImagePulls         *bool             `yaml:"serializeImagePulls,omitempty"`
+	ContainerLogMaxSize         string            `yaml:"containerLogMaxSize,omitempty"`
+	ContainerLogMaxFiles        int               `yaml:"containerLogMaxFiles,omitempty"`
+	RotateCertificates          *bool             `yaml:"rotateCertificates,omitempty"`
+	ServerTLSBootstrap          *bool             `yaml:"serverTLSBootstrap,omitempty"`
+}
+
+func (k *Kubelet) merge(override *Kubelet) *Kubelet {
+	if k == nil && override == nil {
+		return nil
+	}
+	var merged Kubelet
+	if k != nil {
+		merged = *k
+	}
+	if override == nil {
+		return &merged
+	}
+	merged.SystemReserved = mergeMaps(merged.SystemReserved, override.SystemReserved)
+	merged.KubeReserved = mergeMaps(merged.KubeReserved, override.KubeReserved)
+	merged.EvictionHard = mergeMaps(merged.EvictionHard, override.EvictionHard)
+	merged.EvictionSoft = mergeMaps(merged.EvictionSoft, override.EvictionSoft)
+	merged.EvictionSoftGracePeriod = mergeMaps(merged.EvictionSoftGracePeriod, override.EvictionSoftGracePeriod)
+	if override.MaxPods != 0 {
+		merged.MaxPods = override.MaxPods
+	}
+	if override.ImageGCHighThresholdPercent != nil {
+		merged.ImageGCHighThresholdPercent = override.ImageGCHighThresholdPercent
+	}
+	if override.ImageGCLowThresholdPercent != nil {
+		merged.ImageGCLowThresholdPercent = override.ImageGCLowThresholdPercent
+	}
+	if override.SerializeImagePulls != nil {
+		merged.SerializeImagePulls = override.SerializeImagePulls
+	}
+	if override.ContainerLogMaxSize != "" {
+		merged.ContainerLogMaxSize = override.ContainerLogMaxSize
+	}
+	if override.ContainerLogMaxFiles != 0 {
+		merged.ContainerLogMaxFiles = override.ContainerLogMaxFiles
+	}
+	if override.RotateCertificates != nil {
+		merged.RotateCertificates = override.RotateCertificates
+	}
+	if override.ServerTLSBootstrap != nil {
+		merged.ServerTLSBootstrap = override.ServerTLSBootstrap
+	}
+	return &merged
+}
+
+func (k *Kubelet) validate(owner string) error {
+	if k == nil {
+		return nil
+	}
+	if k.MaxPods < 0 {
+		return fmt.Errorf("kubelet maxPods of %s must be positive", owner)
+	}
+	if k.ContainerLogMaxFiles != 0 && k.ContainerLogMaxFiles < 2 {
+		return fmt.Errorf("kubelet containerLogMaxFiles of %s must be at least 2", owner)
+	}
+	for _, percent := range []*int{k.ImageGCHighThresholdPercent, k.ImageGCLowThresholdPercent} {
+		if percent != nil && (*percent < 0 || *percent > 100) {
+			return fmt.Errorf("kubelet image GC thresholds of %s must be between 0 and 100", owner)
+		}
+	}
+	if k.ImageGCHighThresholdPercent != nil && k.ImageGCLowThresholdPercent != nil &&
+		*k.ImageGCLowThresholdPercent >= *k.ImageGCHighThresholdPercent {
+		return fmt.Errorf("kubelet imageGCLowThresholdPercent of %s must be lower than "+
+			"imageGCHighThresholdPercent", owner)
+	}
+	for signal := range k.EvictionSoft {
+		if _, ok := k.EvictionSoftGracePeriod[signal]; !ok {
+			return fmt.Errorf("kubelet evictionSoft \"%s\" of %s requires evictionSoftGracePeriod", signal, owner)
+		}
+	}
+	return nil
+}
+
+func (dc *DeploymentConfig) ValidateKubelet() error {
+	err := dc.Kubernetes.Kubelet.validate("cluster")
+	if err != nil {
+		return err
+	}
+	for _, node := range dc.Nodes {
+		settings := dc.GetNodeSettings(node)
+		err = dc.Kubernetes.Kubelet.merge(settings.Kubelet).validate(fmt.Sprintf("\"%s\"", node.Hostname))
+		if err != nil {
+			return err
+		}
+		err = validatePatches(settings.Patches)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateKubeletVersion checks that kubeadm of kubeVersion can configure kubelet of each node on its own. Kubelet
+// settings of a node are given to kubeadm as a kubeletconfiguration patch.
+func (dc *DeploymentConfig) ValidateKubeletVersion(kubeVersion string) error {
+	kubeSemVer, err := version.NewVersion(kubeVersion)
+	if err != nil {
+		return err
+	}
+	kube125Ver, _ := version.NewVersion("1.25")
+	if !kubeSemVer.LessThan(kube125Ver) {
+		return nil
+	}
+	for _, node := range dc.Nodes {
+		settings := dc.GetNodeSettings(node)
+		kubeletPatch := settings.Kubelet != nil
+		for _, patch := range append(append([]Patch{}, dc.Kubernetes.Patches...), settings.Patches...) {
+			kubeletPatch = kubeletPatch || patch.Target == "kubeletconfiguration"
+		}
+		if kubeletPatch {
+			return fmt.Errorf("kubelet settings and kubeletconfiguration patches of \"%s\" are supported since "+
+				"kubernetes 1.25", node.Hostname)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestKubeletMerge(t *testing.T) {
+	cluster := &Kubelet{
+		MaxPods:                     150,
+		SystemReserved:              map[string]string{"cpu": "500m", "memory": "1Gi"},
+		ImageGCHighThresholdPercent: intPtr(85),
+		SerializeImagePulls:         boolPtr(true),
+		RotateCertificates:          boolPtr(true),
+	}
+	tests := []struct {
+		name     string
+		override *Kubelet
+		expected *Kubelet
+	}{
+		{"no override", nil, cluster},
+		{"override wins", &Kubelet{
+			MaxPods:                     60,
+			SystemReserved:              map[string]string{"memory": "2Gi"},
+			ImageGCHighThresholdPercent: intPtr(90),
+		}, &Kubelet{
+			MaxPods:                     60,
+			SystemReserved:              map[string]string{"cpu": "500m", "memory": "2Gi"},
+			ImageGCHighThresholdPercent: intPtr(90),
+			SerializeImagePulls:         boolPtr(true),
+			RotateCertificates:          boolPtr(true),
+		}},
+		{"explicit false wins", &Kubelet{SerializeImagePulls: boolPtr(false)}, &Kubelet{
+			MaxPods:                     150,
+			SystemReserved:              map[string]string{"cpu": "500m", "memory": "1Gi"},
+			ImageGCHighThresholdPercent: intPtr(85),
+			SerializeImagePulls:         boolPtr(false),
+			RotateCertificates:          boolPtr(true),
+		}},
+		{"explicit zero wins", &Kubelet{ImageGCHighThresholdPercent: intPtr(0)}, &Kubelet{
+			MaxPods:                     150,
+			SystemReserved:              map[string]string{"cpu": "500m", "memory": "1Gi"},
+			ImageGCHighThresholdPercent: intPtr(0),
+			SerializeImagePulls:         boolPtr(true),
+			RotateCertificates:          boolPtr(true),
+		}},
+	}
+	for _, test := range tests {
+		if actual := cluster.merge(test.override); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, actual)
+		}
+	}
+	if merged := (*Kubelet)(nil).merge(nil); merged != nil {
+		t.Errorf("expected nil without kubelet settings, got %+v", merged)
+	}
+}
+
+func TestKubeletPrecedence(t *testing.T) {
+	tests := []struct {
+		role, group, node *Kubelet
+		expected          int
+	}{
+		{nil, nil, nil, 150},
+		{&Kubelet{MaxPods: 110}, nil, nil, 110},
+		{&Kubelet{MaxPods: 110}, &Kubelet{MaxPods: 60}, nil, 60},
+		{&Kubelet{MaxPods: 110}, &Kubelet{MaxPods: 60}, &Kubelet{MaxPods: 30}, 30},
+		{nil, &Kubelet{MaxPods: 60}, &Kubelet{SerializeImagePulls: boolPtr(false)}, 60},
+	}
+	for i, test := range tests {
+		dc := DeploymentConfig{
+			Nodes: []KubeNode{{Hostname: "node1", KubeType: "worker", Group: "infra",
+				NodeSettings: NodeSettings{Kubelet: test.node}}},
+			Roles:      map[string]NodeSettings{"worker": {Kubelet: test.role}},
+			NodeGroups: map[string]NodeSettings{"infra": {Kubelet: test.group}},
+			Kubernetes: Kubernetes{Kubelet: &Kubelet{MaxPods: 150}},
+		}
+		merged := dc.Kubernetes.Kubelet.merge(dc.GetNodeSettings(dc.Nodes[0]).Kubelet)
+		if merged.MaxPods != test.expected {
+			t.Errorf("%d: expected max pods %d, got %d", i, test.expected, merged.MaxPods)
+		}
+	}
+}
+
+func TestValidateKubelet(t *testing.T) {
+	tests := []struct {
+		name    string
+		cluster *Kubelet
+		role    *Kubelet
+		group   *Kubelet
+		node    *Kubelet
+		err     string
+	}{
+		{"negative max pods of node", nil, nil, nil, &Kubelet{MaxPods: -1}, "maxPods of \"node1\""},
+		{"soft eviction grace period of node", nil, nil,
+			&Kubelet{EvictionSoft: map[string]string{"memory.available": "1Gi"}},
+			&Kubelet{EvictionSoftGracePeriod: map[string]string{"memory.available": "1m"}}, ""},
+		{"soft eviction without grace period", nil, nil,
+			&Kubelet{EvictionSoft: map[string]string{"memory.available": "1Gi"}},
+			&Kubelet{EvictionSoftGracePeriod: map[string]string{"nodefs.available": "1m"}}, "evictionSoftGracePeriod"},
+		{"image gc low over cluster high", &Kubelet{ImageGCHighThresholdPercent: intPtr(85)}, nil, nil,
+			&Kubelet{ImageGCLowThresholdPercent: intPtr(90)}, "imageGCLowThresholdPercent of \"node1\""},
+		{"image gc high of role", &Kubelet{ImageGCLowThresholdPercent: intPtr(70)},
+			&Kubelet{ImageGCHighThresholdPercent: intPtr(85)}, nil, nil, ""},
+		{"image gc out of range", nil, &Kubelet{ImageGCHighThresholdPercent: intPtr(101)}, nil, nil,
+			"between 0 and 100"},
+		{"container log max files", &Kubelet{ContainerLogMaxFiles: 1}, nil, nil, nil, "of cluster"},
+	}
+	for _, test := range tests {
+		dc := DeploymentConfig{
+			Nodes: []KubeNode{{Hostname: "node1", KubeType: "worker", Group: "infra",
+				NodeSettings: NodeSettings{Kubelet: test.node}}},
+			Roles:      map[string]NodeSettings{"worker": {Kubelet: test.role}},
+			NodeGroups: map[string]NodeSettings{"infra": {Kubelet: test.group}},
+			Kubernetes: Kubernetes{Kubelet: test.cluster},
+		}
+		err := dc.ValidateKubelet()
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error containing \"%s\", got %v", test.name, test.err, err)
+		}
+	}
+}
+
+func TestValidateKubeletVersion(t *testing.T) {
+	kubeletPatch := Patch{Target: "kubeletconfiguration", Patch: "maxPods: 60"}
+	tests := []struct {
+		kubeVersion string
+		dc          DeploymentConfig
+		valid       bool
+	}{
+		{"1.24.17", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1"}},
+			Kubernetes: Kubernetes{Kubelet: &Kubelet{MaxPods: 150}}}, true},
+		{"1.24.17", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1",
+			NodeSettings: NodeSettings{Kubelet: &Kubelet{MaxPods: 60}}}}}, false},
+		{"1.24.17", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1"}},
+			Kubernetes: Kubernetes{Patches: []Patch{kubeletPatch}}}, false},
+		{"1.25.0", DeploymentConfig{Nodes: []KubeNode{{Hostname: "node1",
+			NodeSettings: NodeSettings{Kubelet: &Kubelet{MaxPods: 60}, Patches: []Patch{kubeletPatch}}}}}, true},
+	}
+	for _, test := range tests {
+		if err := test.dc.ValidateKubeletVersion(test.kubeVersion); (err == nil) != test.valid {
+			t.Errorf("ValidateKubeletVersion(%s) of %+v = %v, expected valid: %t", test.kubeVersion, test.dc, err,
+				test.valid)
+		}
+	}
+}
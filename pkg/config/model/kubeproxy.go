@@ -0,0 +1,89 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	KubeProxyModeIPTables = "iptables"
+	KubeProxyModeIPVS     = "ipvs"
+	KubeProxyModeNFTables = "nftables"
+)
+
+var (
+	kubeProxyModes = []string{KubeProxyModeIPTables, KubeProxyModeIPVS, KubeProxyModeNFTables}
+	ipvsSchedulers = []string{"rr", "wrr", "lc", "wlc", "lblc", "lblcr", "dh", "sh", "sed", "nq", "mh"}
+)
+
+type KubeProxy struct {
+	// Mode is iptables, ipvs or nftables, kube-proxy runs in iptables mode if it is empty
+	Mode string `yaml:"mode,omitempty"`
+	// Skip leaves kube-proxy out, for CNIs which replace it
+	Skip bool          `yaml:"skip,omitempty"`
+	IPVS KubeProxyIPVS `yaml:"ipvs,omitempty"`
+}
+
+type KubeProxyIPVS struct {
+	Scheduler string `yaml:"scheduler,omitempty" json:"scheduler,omitempty"`
+	// StrictARP stops nodes answering ARP requests of service IPs, MetalLB and kube-vip need it in ipvs mode
+	StrictARP bool `yaml:"strictARP,omitempty" json:"strictARP,omitempty"`
+}
+
+func (kp KubeProxy) GetMode() string {
+	if kp.Mode == "" {
+		return KubeProxyModeIPTables
+	}
+	return kp.Mode
+}
+
+// KernelModules returns the modules kube-proxy needs in its mode.
+func (kp KubeProxy) KernelModules() []string {
+	if kp.Skip {
+		return nil
+	}
+	switch kp.GetMode() {
+	case KubeProxyModeIPVS:
+		modules := []string{"ip_vs", "ip_vs_rr", "ip_vs_wrr", "ip_vs_sh"}
+		if kp.IPVS.Scheduler != "" && !contains(modules, "ip_vs_"+kp.IPVS.Scheduler) {
+			modules = append(modules, "ip_vs_"+kp.IPVS.Scheduler)
+		}
+		return append(modules, "nf_conntrack")
+	case KubeProxyModeNFTables:
+		return []string{"nf_tables", "nf_conntrack"}
+	}
+	return []string{"nf_conntrack"}
+}
+
+// Packages returns the packages kube-proxy needs on nodes in its mode.
+func (kp KubeProxy) Packages() []string {
+	if kp.Skip {
+		return nil
+	}
+	switch kp.GetMode() {
+	case KubeProxyModeIPVS:
+		return []string{"ipvsadm", "ipset"}
+	case KubeProxyModeNFTables:
+		return []string{"nftables"}
+	}
+	return nil
+}
+
+func (dc *DeploymentConfig) ValidateKubeProxy() error {
+	kp := dc.Kubernetes.KubeProxy
+	if kp.Skip && (kp.Mode != "" || kp.IPVS != (KubeProxyIPVS{})) {
+		return fmt.Errorf("kube-proxy mode and ipvs settings can not be used when kube-proxy is skipped")
+	}
+	if !contains(kubeProxyModes, kp.GetMode()) {
+		return fmt.Errorf("unknown kube-proxy mode \"%s\", valid modes: %s", kp.Mode,
+			strings.Join(kubeProxyModes, ", "))
+	}
+	if kp.GetMode() != KubeProxyModeIPVS && kp.IPVS != (KubeProxyIPVS{}) {
+		return fmt.Errorf("kube-proxy ipvs settings require ipvs mode")
+	}
+	if kp.IPVS.Scheduler != "" && !contains(ipvsSchedulers, kp.IPVS.Scheduler) {
+		return fmt.Errorf("unknown ipvs scheduler \"%s\", valid schedulers: %s", kp.IPVS.Scheduler,
+			strings.Join(ipvsSchedulers, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+
+	"com.github.tunahansezen/tkube/pkg/constant"
+)
+
+// LoadBalancer balances apiserver traffic across masters behind the control plane virtual IP.
+type LoadBalancer struct {
+	Type string `yaml:"type,omitempty"`
+	Port int    `yaml:"port,omitempty"`
+}
+
+func (lb LoadBalancer) Enabled() bool {
+	return lb.Type == constant.LoadBalancerHAProxy
+}
+
+func (lb LoadBalancer) GetPort() int {
+	if lb.Port == 0 {
+		return constant.DefaultLoadBalancerPort
+	}
+	return lb.Port
+}
+
+// GetControlPlanePort returns the port apiserver is reached from the control plane endpoint.
+func (dc *DeploymentConfig) GetControlPlanePort() int {
+	if dc.LoadBalancer.Enabled() {
+		return dc.LoadBalancer.GetPort()
+	}
+	return constant.ApiServerPort
+}
+
+func (dc *DeploymentConfig) ValidateLoadBalancer() error {
+	lb := dc.LoadBalancer
+	if lb.Type == "" {
+		return nil
+	}
+	if !lb.Enabled() {
+		return fmt.Errorf("unknown load balancer \"%s\", valid load balancers: %s", lb.Type,
+			constant.LoadBalancerHAProxy)
+	}
+	if !dc.VIPEnabled() {
+		return fmt.Errorf("load balancer \"%s\" requires a control plane virtual IP", lb.Type)
+	}
+	if lb.GetPort() == constant.ApiServerPort || lb.GetPort() < 1 || lb.GetPort() > 65535 {
+		return fmt.Errorf("load balancer port must be between 1 and 65535 and different from apiserver port %d",
+			constant.ApiServerPort)
+	}
+	return nil
+}
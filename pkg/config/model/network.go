@@ -0,0 +1,128 @@
+package model
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SplitCIDRs splits a subnet of deployment config, which is a CIDR or an IPv4 and IPv6 CIDR pair separated by comma
+// for dual-stack.
+func SplitCIDRs(subnet string) []string {
+	var cidrs []string
+	for _, cidr := range strings.Split(subnet, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return cidrs
+}
+
+func isIPv6CIDR(cidr string) bool {
+	ip, _, err := net.ParseCIDR(cidr)
+	return err == nil && ip.To4() == nil
+}
+
+func familyName(ipv6 bool) string {
+	if ipv6 {
+		return "IPv6"
+	}
+	return "IPv4"
+}
+
+// DualStack reports whether pods get both IPv4 and IPv6 addresses.
+func (dc *DeploymentConfig) DualStack() bool {
+	return len(SplitCIDRs(dc.Kubernetes.PodSubnet)) == 2
+}
+
+// IPv6Enabled reports whether pods get IPv6 addresses, in an IPv6 only or a dual-stack cluster.
+func (dc *DeploymentConfig) IPv6Enabled() bool {
+	for _, cidr := range SplitCIDRs(dc.Kubernetes.PodSubnet) {
+		if isIPv6CIDR(cidr) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPodCIDR returns the pod CIDR of the family, empty if pods get no address of it.
+func (dc *DeploymentConfig) GetPodCIDR(ipv6 bool) string {
+	for _, cidr := range SplitCIDRs(dc.Kubernetes.PodSubnet) {
+		if isIPv6CIDR(cidr) == ipv6 {
+			return cidr
+		}
+	}
+	return ""
+}
+
+// GetNodeIPs returns the addresses kubelet reports for node, the primary one comes first.
+func (dc *DeploymentConfig) GetNodeIPs(node KubeNode) []net.IP {
+	primary := node.NodeIP
+	if primary == nil {
+		primary = node.IP
+	}
+	ips := []net.IP{primary}
+	if dc.DualStack() && node.IPv6 != nil {
+		ips = append(ips, node.IPv6)
+	}
+	return ips
+}
+
+// AddressOfFamily returns the address of the node in the family of ip, nil if the node has none.
+func (kn KubeNode) AddressOfFamily(ip net.IP) net.IP {
+	ipv6 := ip.To4() == nil
+	if (kn.IP.To4() == nil) == ipv6 {
+		return kn.IP
+	}
+	if ipv6 {
+		return kn.IPv6
+	}
+	return nil
+}
+
+func (dc *DeploymentConfig) validateNetworking() error {
+	k := dc.Kubernetes
+	subnets := map[string][]string{
+		"podSubnet":     SplitCIDRs(k.PodSubnet),
+		"serviceSubnet": SplitCIDRs(k.ServiceSubnet),
+	}
+	for _, name := range SortedKeys(subnets) {
+		cidrs := subnets[name]
+		for _, cidr := range cidrs {
+			if _, _, err := net.ParseCIDR(cidr); err != nil {
+				return fmt.Errorf("invalid subnet \"%s\": %w", cidr, err)
+			}
+		}
+		if len(cidrs) > 2 || (len(cidrs) == 2 && isIPv6CIDR(cidrs[0]) == isIPv6CIDR(cidrs[1])) {
+			return fmt.Errorf("kubernetes.%s must be a CIDR or an IPv4 and IPv6 CIDR pair", name)
+		}
+	}
+	pods, services := subnets["podSubnet"], subnets["serviceSubnet"]
+	if len(pods) == 0 {
+		return nil
+	}
+	if dc.DualStack() && len(services) != 2 {
+		return fmt.Errorf("dual-stack requires kubernetes.serviceSubnet with an IPv4 and IPv6 CIDR pair")
+	}
+	if len(services) > 0 && (len(services) != len(pods) || isIPv6CIDR(services[0]) != isIPv6CIDR(pods[0])) {
+		return fmt.Errorf("kubernetes.serviceSubnet must have the same IP families as kubernetes.podSubnet")
+	}
+	primaryIPv6 := isIPv6CIDR(pods[0])
+	if dc.DualStack() && primaryIPv6 {
+		return fmt.Errorf("dual-stack with IPv6 as primary family is not supported, IPv4 CIDRs must come first")
+	}
+	for _, node := range dc.Nodes {
+		primary := dc.GetNodeIPs(node)[0]
+		if primary != nil && (primary.To4() == nil) != primaryIPv6 {
+			return fmt.Errorf("address of \"%s\" must be %s like the first CIDR of kubernetes.podSubnet",
+				node.Hostname, familyName(primaryIPv6))
+		}
+		if node.IPv6 != nil && node.IPv6.To4() != nil {
+			return fmt.Errorf("ipv6 of \"%s\" is not an IPv6 address", node.Hostname)
+		}
+		if dc.DualStack() && node.IPv6 == nil {
+			return fmt.Errorf("ipv6 of \"%s\" must be set for dual-stack", node.Hostname)
+		}
+	}
+	return nil
+}
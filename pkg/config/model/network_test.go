@@ -0,0 +1,48 @@
+package model
+
+import (
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestValidateNetworking(t *testing.T) {
+	v4Node := KubeNode{Hostname: "node1", IP: net.ParseIP("192.168.50.10")}
+	v6Node := KubeNode{Hostname: "node1", IP: net.ParseIP("fd00:50::10")}
+	dualNode := KubeNode{Hostname: "node1", IP: net.ParseIP("192.168.50.10"), IPv6: net.ParseIP("fd00:50::10")}
+	tests := []struct {
+		name                     string
+		podSubnet, serviceSubnet string
+		node                     KubeNode
+		err                      string
+	}{
+		{"ipv4", "10.244.0.0/16", "10.96.0.0/12", v4Node, ""},
+		{"ipv6", "fd00:10:244::/56", "fd00:10:96::/112", v6Node, ""},
+		{"dual-stack", "10.244.0.0/16,fd00:10:244::/56", "10.96.0.0/12,fd00:10:96::/112", dualNode, ""},
+		{"no subnets", "", "", v4Node, ""},
+		{"invalid cidr", "10.244.0.0/33", "", v4Node, "invalid subnet"},
+		{"same family pair", "10.244.0.0/16,10.245.0.0/16", "", v4Node, "kubernetes.podSubnet must be"},
+		{"three cidrs", "10.244.0.0/16,fd00:10:244::/56,10.245.0.0/16", "", v4Node, "kubernetes.podSubnet must be"},
+		{"dual-stack single service subnet", "10.244.0.0/16,fd00:10:244::/56", "10.96.0.0/12", dualNode,
+			"dual-stack requires kubernetes.serviceSubnet"},
+		{"service family differs", "10.244.0.0/16", "fd00:10:96::/112", v4Node, "same IP families"},
+		{"ipv6 primary", "fd00:10:244::/56,10.244.0.0/16", "fd00:10:96::/112,10.96.0.0/12", dualNode,
+			"IPv4 CIDRs must come first"},
+		{"node family differs", "fd00:10:244::/56", "", v4Node, "must be IPv6"},
+		{"node ipv6 is ipv4", "10.244.0.0/16", "", KubeNode{Hostname: "node1", IP: net.ParseIP("192.168.50.10"),
+			IPv6: net.ParseIP("192.168.50.11")}, "is not an IPv6 address"},
+		{"dual-stack without node ipv6", "10.244.0.0/16,fd00:10:244::/56", "10.96.0.0/12,fd00:10:96::/112", v4Node,
+			"must be set for dual-stack"},
+	}
+	for _, test := range tests {
+		dc := DeploymentConfig{Nodes: []KubeNode{test.node}}
+		dc.Kubernetes.PodSubnet = test.podSubnet
+		dc.Kubernetes.ServiceSubnet = test.serviceSubnet
+		err := dc.validateNetworking()
+		if test.err == "" && err != nil {
+			t.Errorf("%s: unexpected error: %s", test.name, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("%s: expected error containing \"%s\", got %v", test.name, test.err, err)
+		}
+	}
+}
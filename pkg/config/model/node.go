@@ -0,0 +1,112 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeSettings can be defined for a role (kubeType), for a node group and for a node itself. The node wins over its
+// group, and the group wins over the role.
+type NodeSettings struct {
+	Labels           map[string]string `yaml:"labels,omitempty"`
+	Taints           []Taint           `yaml:"taints,omitempty"`
+	KubeletExtraArgs map[string]string `yaml:"kubeletExtraArgs,omitempty"`
+	// Kubelet overrides kubernetes.kubelet for the node
+	Kubelet *Kubelet `yaml:"kubelet,omitempty"`
+	Patches []Patch  `yaml:"patches,omitempty"`
+}
+
+type Taint struct {
+	Key    string `yaml:"key" json:"key"`
+	Value  string `yaml:"value,omitempty" json:"value,omitempty"`
+	Effect string `yaml:"effect" json:"effect"`
+}
+
+func (t Taint) String() string {
+	if t.Value == "" {
+		return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+	}
+	return fmt.Sprintf("%s=%s:%s", t.Key, t.Value, t.Effect)
+}
+
+// ID identifies a taint on a node, there can be only one taint with the same key and effect.
+func (t Taint) ID() string {
+	return fmt.Sprintf("%s:%s", t.Key, t.Effect)
+}
+
+func (ns NodeSettings) merge(override NodeSettings) NodeSettings {
+	merged := NodeSettings{
+		Labels:           mergeMaps(ns.Labels, override.Labels),
+		KubeletExtraArgs: mergeMaps(ns.KubeletExtraArgs, override.KubeletExtraArgs),
+		Kubelet:          ns.Kubelet.merge(override.Kubelet),
+		Patches:          append(append([]Patch{}, ns.Patches...), override.Patches...),
+	}
+	index := make(map[string]int)
+	for _, taint := range append(append([]Taint{}, ns.Taints...), override.Taints...) {
+		if i, exists := index[taint.ID()]; exists {
+			merged.Taints[i] = taint
+			continue
+		}
+		index[taint.ID()] = len(merged.Taints)
+		merged.Taints = append(merged.Taints, taint)
+	}
+	return merged
+}
+
+func mergeMaps(base map[string]string, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string)
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
+// GetNodeSettings returns the effective settings of node after applying its role and group defaults.
+func (dc *DeploymentConfig) GetNodeSettings(node KubeNode) NodeSettings {
+	settings := dc.Roles[node.KubeType]
+	if node.Group != "" {
+		settings = settings.merge(dc.NodeGroups[node.Group])
+	}
+	return settings.merge(node.NodeSettings)
+}
+
+func (dc *DeploymentConfig) ValidateNodeSettings() error {
+	for role := range dc.Roles {
+		if role != "master" && role != "worker" {
+			return fmt.Errorf("unknown role \"%s\" in roles, valid roles: master, worker", role)
+		}
+	}
+	for _, node := range dc.Nodes {
+		if node.Group != "" {
+			if _, exists := dc.NodeGroups[node.Group]; !exists {
+				return fmt.Errorf("node group \"%s\" of \"%s\" is not defined in nodeGroups", node.Group,
+					node.Hostname)
+			}
+		}
+		for _, taint := range dc.GetNodeSettings(node).Taints {
+			switch taint.Effect {
+			case "NoSchedule", "PreferNoSchedule", "NoExecute":
+			default:
+				return fmt.Errorf("taint \"%s\" of \"%s\" has invalid effect \"%s\"", taint.Key, node.Hostname,
+					taint.Effect)
+			}
+		}
+	}
+	return nil
+}
+
+// SortedKeys returns keys of m in order, so generated files and commands do not change between runs.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
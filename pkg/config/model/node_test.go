@@ -0,0 +1,57 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetNodeSettings(t *testing.T) {
+	dc := DeploymentConfig{
+		Roles: map[string]NodeSettings{"worker": {
+			Labels:           map[string]string{"role": "worker", "zone": "a"},
+			Taints:           []Taint{{Key: "dedicated", Value: "worker", Effect: "NoSchedule"}},
+			KubeletExtraArgs: map[string]string{"v": "2"},
+			Patches:          []Patch{{Target: "kubeletconfiguration", Patch: "role"}},
+		}},
+		NodeGroups: map[string]NodeSettings{"infra": {
+			Labels: map[string]string{"zone": "b"},
+			Taints: []Taint{{Key: "dedicated", Value: "infra", Effect: "NoSchedule"},
+				{Key: "dedicated", Value: "infra", Effect: "NoExecute"}},
+			Patches: []Patch{{Target: "kubeletconfiguration", Patch: "group"}},
+		}},
+	}
+	tests := []struct {
+		name     string
+		node     KubeNode
+		expected NodeSettings
+	}{
+		{"role", KubeNode{KubeType: "worker"}, dc.Roles["worker"]},
+		{"group over role", KubeNode{KubeType: "worker", Group: "infra"}, NodeSettings{
+			Labels: map[string]string{"role": "worker", "zone": "b"},
+			Taints: []Taint{{Key: "dedicated", Value: "infra", Effect: "NoSchedule"},
+				{Key: "dedicated", Value: "infra", Effect: "NoExecute"}},
+			KubeletExtraArgs: map[string]string{"v": "2"},
+			Patches: []Patch{{Target: "kubeletconfiguration", Patch: "role"},
+				{Target: "kubeletconfiguration", Patch: "group"}},
+		}},
+		{"node over group", KubeNode{KubeType: "worker", Group: "infra", NodeSettings: NodeSettings{
+			Labels:           map[string]string{"zone": "c"},
+			Taints:           []Taint{{Key: "dedicated", Effect: "NoExecute"}},
+			KubeletExtraArgs: map[string]string{"v": "4"},
+		}}, NodeSettings{
+			Labels: map[string]string{"role": "worker", "zone": "c"},
+			Taints: []Taint{{Key: "dedicated", Value: "infra", Effect: "NoSchedule"},
+				{Key: "dedicated", Effect: "NoExecute"}},
+			KubeletExtraArgs: map[string]string{"v": "4"},
+			Patches: []Patch{{Target: "kubeletconfiguration", Patch: "role"},
+				{Target: "kubeletconfiguration", Patch: "group"}},
+		}},
+		{"master without role", KubeNode{KubeType: "master"}, NodeSettings{Patches: []Patch{}}},
+	}
+	for _, test := range tests {
+		actual := dc.GetNodeSettings(test.node)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("%s: expected %+v, got %+v", test.name, test.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package schema
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"strings"
+
+	"com.github.tunahansezen/tkube/pkg/config/migration"
+	"com.github.tunahansezen/tkube/pkg/config/model"
+)
+
+const (
+	draft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+var ipType = reflect.TypeOf(net.IP{})
+
+// DeploymentConfig returns the JSON Schema of deployment.yaml generated from model.DeploymentConfig.
+func DeploymentConfig() ([]byte, error) {
+	s := generate(reflect.TypeOf(model.DeploymentConfig{}))
+	s["$schema"] = draft
+	s["title"] = "tkube deployment config"
+	properties := s["properties"].(map[string]interface{})
+	properties["apiVersion"] = map[string]interface{}{"const": migration.CurrentApiVersion}
+	properties["kind"] = map[string]interface{}{"const": migration.Kind}
+	return json.MarshalIndent(s, "", "  ")
+}
+
+func generate(t reflect.Type) map[string]interface{} {
+	if t == ipType {
+		return map[string]interface{}{
+			"type":  "string",
+			"anyOf": []interface{}{map[string]interface{}{"format": "ipv4"}, map[string]interface{}{"format": "ipv6"}},
+		}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return generate(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": generate(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": generate(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		addProperties(t, properties)
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{}
+}
+
+func addProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, inline := yamlName(field)
+		if name == "-" {
+			continue
+		}
+		if inline {
+			addProperties(field.Type, properties)
+			continue
+		}
+		properties[name] = generate(field.Type)
+	}
+}
+
+// yamlName returns the key yaml.v3 uses for the field.
+func yamlName(field reflect.StructField) (name string, inline bool) {
+	tag := field.Tag.Get("yaml")
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "inline" {
+			return "", true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], false
+	}
+	return strings.ToLower(field.Name), false
+}
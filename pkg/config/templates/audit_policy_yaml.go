@@ -0,0 +1,51 @@
+package templates
+
+import (
+	"github.com/lithammer/dedent"
+	"text/template"
+)
+
+var (
+	// AuditPolicyYaml renders the audit policy presets. "default" drops noisy requests, keeps secrets and configmaps
+	// at metadata level and bodies of write requests, "metadata" logs metadata of every request.
+	AuditPolicyYaml = template.Must(template.New("policy.yaml").Parse(
+		dedent.Dedent(`apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+  - RequestReceived
+rules:
+{{- if eq .Preset "metadata" }}
+  - level: Metadata
+{{- else }}
+  - level: None
+    users: ["system:kube-proxy"]
+    verbs: ["watch"]
+    resources:
+      - group: ""
+        resources: ["endpoints", "services", "services/status"]
+  - level: None
+    userGroups: ["system:nodes"]
+    verbs: ["get"]
+    resources:
+      - group: ""
+        resources: ["nodes", "nodes/status"]
+  - level: None
+    nonResourceURLs: ["/healthz*", "/livez*", "/readyz*", "/version"]
+  - level: None
+    resources:
+      - group: ""
+        resources: ["events"]
+      - group: "events.k8s.io"
+        resources: ["events"]
+  - level: Metadata
+    resources:
+      - group: ""
+        resources: ["secrets", "configmaps", "serviceaccounts/token"]
+      - group: "authentication.k8s.io"
+        resources: ["tokenreviews"]
+  - level: Request
+    verbs: ["create", "update", "patch", "delete", "deletecollection"]
+  - level: Metadata
+{{- end }}
+`)))
+)
@@ -15,7 +15,7 @@ errorExit() {
 
 curl --silent --max-time 2 --insecure https://localhost:6443/ -o /dev/null || errorExit "Error GET https://localhost:6443/"
 if ip addr | grep -q {{ .VirtualIP }}; then
-    curl --silent --max-time 2 --insecure https://{{ .VirtualIP }}:6443/ -o /dev/null || errorExit "Error GET https://{{ .VirtualIP }}:6443/"
+    curl --silent --max-time 2 --insecure https://{{ .VirtualIPHost }}:{{ .Port }}/ -o /dev/null || errorExit "Error GET https://{{ .VirtualIPHost }}:{{ .Port }}/"
 fi
 `)))
 )
@@ -0,0 +1,41 @@
+package templates
+
+import (
+	"github.com/lithammer/dedent"
+	"text/template"
+)
+
+var (
+	// HAProxyCfg binds to all addresses, so backup masters can start it before they hold the virtual IP
+	HAProxyCfg = template.Must(template.New("haproxy.cfg").Parse(
+		dedent.Dedent(`global
+    log /dev/log local0
+    daemon
+
+defaults
+    mode tcp
+    log global
+    option tcplog
+    option dontlognull
+    retries 1
+    timeout connect 10s
+    timeout client 300s
+    timeout server 300s
+
+frontend apiserver
+{{- if .IPv6 }}
+    bind :::{{ .Port }} v4v6
+{{- else }}
+    bind *:{{ .Port }}
+{{- end }}
+    default_backend apiserver
+
+backend apiserver
+    option httpchk GET /healthz
+    http-check expect status 200
+    balance roundrobin
+{{- range .Servers }}
+    server {{ .Name }} {{ .Address }} check check-ssl verify none inter 2s fall 3 rise 2
+{{- end }}
+`)))
+)
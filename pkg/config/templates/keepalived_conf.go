@@ -13,27 +13,59 @@ var (
 
 vrrp_script check_apiserver {
   script "/etc/keepalived/check_apiserver.sh"
-  interval 3
-  weight -2
-  fall 10
-  rise 2
+  interval {{ .Check.Interval }}
+  weight {{ .Check.Weight }}
+  fall {{ .Check.Fall }}
+  rise {{ .Check.Rise }}
 }
 
 vrrp_instance VI_1 {
-    state MASTER
+    state {{ .State }}
     interface {{ .Interface }}
     virtual_router_id {{ .VirtualRouterID }}
     priority {{ .Priority }}
+{{- if .NoPreempt }}
+    nopreempt
+{{- end }}
+{{- if .IPv6 }}
+    native_ipv6
+{{- else }}
     authentication {
         auth_type PASS
         auth_pass {{ .AuthPass }}
     }
+{{- end }}
+{{- if .Unicast }}
+    unicast_src_ip {{ .UnicastSrcIP }}
+    unicast_peer {
+{{- range .UnicastPeers }}
+        {{ . }}
+{{- end }}
+    }
+{{- end }}
     virtual_ipaddress {
         {{ .VirtualIP }}
     }
     track_script {
         check_apiserver
     }
+{{- with .Notify }}
+{{- if .Master }}
+    notify_master "{{ .Master }}"
+{{- end }}
+{{- if .Backup }}
+    notify_backup "{{ .Backup }}"
+{{- end }}
+{{- if .Fault }}
+    notify_fault "{{ .Fault }}"
+{{- end }}
+{{- if .Stop }}
+    notify_stop "{{ .Stop }}"
+{{- end }}
+{{- if .Notify }}
+    notify "{{ .Notify }}"
+{{- end }}
+{{- end }}
 }
 `)))
 )
@@ -0,0 +1,78 @@
+package templates
+
+import (
+	"github.com/lithammer/dedent"
+	"text/template"
+)
+
+var (
+	KubeVipYaml = template.Must(template.New("kube-vip.yaml").Parse(
+		dedent.Dedent(`apiVersion: v1
+kind: Pod
+metadata:
+  name: kube-vip
+  namespace: kube-system
+spec:
+  containers:
+    - name: kube-vip
+      image: {{ .Image }}
+      imagePullPolicy: IfNotPresent
+      args:
+        - manager
+      env:
+        - name: address
+          value: "{{ .VirtualIP }}"
+        - name: port
+          value: "{{ .Port }}"
+        - name: vip_cidr
+          value: "{{ .CIDR }}"
+        - name: cp_enable
+          value: "true"
+        - name: cp_namespace
+          value: kube-system
+        - name: vip_leaderelection
+          value: "true"
+        - name: vip_leasename
+          value: plndr-cp-lock
+        - name: vip_leaseduration
+          value: "5"
+        - name: vip_renewdeadline
+          value: "3"
+        - name: vip_retryperiod
+          value: "1"
+{{- if .Interface }}
+        - name: vip_interface
+          value: {{ .Interface }}
+{{- end }}
+{{- if eq .Mode "bgp" }}
+        - name: bgp_enable
+          value: "true"
+        - name: bgp_routerid
+          value: "{{ .RouterID }}"
+        - name: bgp_as
+          value: "{{ .BGP.AS }}"
+        - name: bgp_peers
+          value: "{{ .BGPPeers }}"
+{{- else }}
+        - name: vip_arp
+          value: "true"
+{{- end }}
+      securityContext:
+        capabilities:
+          add:
+            - NET_ADMIN
+            - NET_RAW
+      volumeMounts:
+        - mountPath: /etc/kubernetes/admin.conf
+          name: kubeconfig
+  hostAliases:
+    - hostnames:
+        - kubernetes
+      ip: 127.0.0.1
+  hostNetwork: true
+  volumes:
+    - name: kubeconfig
+      hostPath:
+        path: {{ .KubeConfig }}
+`)))
+)
@@ -6,26 +6,25 @@ import (
 	"io"
 	"net"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
 
-	enc "com.github.tunahansezen/tkube/pkg/encryption"
 	"com.github.tunahansezen/tkube/pkg/util"
 	"github.com/bodgit/sshkrb5"
 	"github.com/guumaster/logsymbols"
 	"github.com/pkg/sftp"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/ssh"
-	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultSSHPort = 22
 )
 
 var (
-	homedir        string
-	sshDataFile    = fmt.Sprintf("%s/ssh", util.LocalDataPath)
-	sshConnections = make(map[string]*ssh.Client)
-	Nodes          = make(map[string]*Node)
+	sshDataFile string
+	Nodes       = make(map[string]*Node)
 )
 
 type Node struct {
@@ -34,6 +33,9 @@ type Node struct {
 	SSHUser           string
 	SSHPass           string
 	SSHPrivateKeyPath string
+	SSHKeyPassphrase  string
+	SudoPass          string
+	Bastion           string
 	Hostname          string
 }
 
@@ -41,9 +43,12 @@ func (n Node) String() string {
 	return n.SSHUser + "@" + n.IP.String()
 }
 
-func init() {
-	homedir, _ = os.UserHomeDir()
-	sshDataFile = strings.ReplaceAll(sshDataFile, "$HOME", homedir)
+// GetSudoPass returns the sudo password of the node, which is the SSH password unless defined separately.
+func (n Node) GetSudoPass() string {
+	if n.SudoPass != "" {
+		return n.SudoPass
+	}
+	return n.SSHPass
 }
 
 func IsReachable(host string, port int) bool {
@@ -97,255 +102,209 @@ func IsReachableURL(url string) bool {
 }
 
 func CheckSSHConnection(node *Node) error {
-	if sshConnections[node.IP.String()] != nil {
+	if getConnection(node.IP.String()) != nil {
 		// already checked
 		return nil
 	}
-	if !IsReachable(node.IP.String(), node.SSHPort) {
-		return errors.New(fmt.Sprintf("%s:%d is not reachable", node.IP.String(), node.SSHPort))
+	fileAuth := fileAuthOf(node)
+	port := sshPort(node, fileAuth)
+	if (fileAuth == nil || fileAuth.Bastion == "") && !IsReachable(node.IP.String(), port) {
+		return errors.New(fmt.Sprintf("%s:%d is not reachable", node.IP.String(), port))
 	}
 	_, err := CreateSshConnection(node)
 	return err
 }
 
 func CreateSshConnection(node *Node) (*ssh.Client, error) {
-	exist := sshConnections[node.IP.String()]
+	exist := getConnection(node.IP.String())
 	if exist != nil {
 		return exist, nil
 	}
+	if client, err := reconnect(node.IP.String()); client != nil || err != nil {
+		return client, err
+	}
 	util.StartSpinner(fmt.Sprintf("Checking SSH connection to %s", node))
-	var auth []ssh.AuthMethod
-	var connection *ssh.Client
-	var err error
-	var usedSSHUser string
-	var usedSSHPass string
-	var usedPrivateKeyPath string
 	var finalMsg string
-	dataSSHUser, dataSSHPass, dataSSHPrivateKey, err := CheckSSHDataForAddr(node.IP.String())
+	auth, err := lookupAuth(node)
 	if err != nil {
 		return nil, err
 	}
-	if dataSSHUser != "" && (dataSSHPass != "" || dataSSHPrivateKey != "") { // read from saved data
-		usedSSHUser = dataSSHUser
-		if dataSSHPass != "" {
-			auth = []ssh.AuthMethod{
-				ssh.Password(dataSSHPass),
-			}
-			usedSSHPass = dataSSHPass
-		} else {
-			auth = getKeyAuth(dataSSHPrivateKey)
-			usedPrivateKeyPath = dataSSHPrivateKey
-		}
-		connection, err = sshDial(dataSSHUser, auth, node.IP.String(), node.SSHPort)
-	} else if node.SSHUser != "" && (node.SSHPass != "" || node.SSHPrivateKeyPath != "") { // read from config
-		usedSSHUser = node.SSHUser
-		if node.SSHPass != "" {
-			auth = []ssh.AuthMethod{
-				ssh.Password(node.SSHPass),
-			}
-			usedSSHPass = node.SSHPass
-		} else {
-			auth = getKeyAuth(node.SSHPrivateKeyPath)
-			usedPrivateKeyPath = node.SSHPrivateKeyPath
-		}
-		connection, err = sshDial(node.SSHUser, auth, node.IP.String(), node.SSHPort)
-	} else { // ask credentials
-		usedSSHUser, err = util.AskString(fmt.Sprintf("Please enter SSH user for %s", node.IP.String()), false,
-			util.CommonValidator)
-		if err != nil {
-			return nil, err
-		}
-		var authMethod string
-		authMethod, err = util.AskChoice("Which method want to use for SSH authentication?",
-			[]string{"password", "private-key", "kerberos"})
+	if auth == nil { // ask credentials
+		auth, err = askAuth(node)
 		if err != nil {
 			return nil, err
-		} else if authMethod == "password" {
-			usedSSHPass, err = util.AskString(fmt.Sprintf("Please enter SSH pass for %s", node.IP.String()), true,
-				util.PasswordValidator)
-			if err != nil {
-				return nil, err
-			}
-			auth = []ssh.AuthMethod{
-				ssh.Password(usedSSHPass),
-			}
-		} else if authMethod == "private-key" {
-			usedPrivateKeyPath, err = util.AskString(
-				fmt.Sprintf("Please enter SSH private key path for %s", node.IP.String()), false, util.PathValidator)
-			if err != nil {
-				return nil, err
-			}
-			auth = getKeyAuth(usedPrivateKeyPath)
-		} else if authMethod == "kerberos" {
-			gssapiClient, err := sshkrb5.NewClient()
-			if err != nil {
-				log.Fatalf("GSSAPI client could not created: %v", err)
-			}
-			defer gssapiClient.Close()
-			hostname := node.Hostname
-			if hostname == "" {
-				hostname, err = util.AskString(
-					fmt.Sprintf("Please enter host address for %s to use kerberos", node.IP.String()), false,
-					util.CommonValidator)
-			}
-			auth = []ssh.AuthMethod{
-				ssh.GSSAPIWithMICAuthMethod(gssapiClient, hostname),
-			}
-		}
-		connection, err = sshDial(usedSSHUser, auth, node.IP.String(), node.SSHPort)
-		if err == nil {
-			finalMsg = fmt.Sprintf("SSH connection successful for %s with user \"%s\"", node.IP.String(), usedSSHUser)
 		}
 	}
-
+	log.Debugf("Using SSH credentials of %s from %s", node.IP.String(), auth.source)
+	port := sshPort(node, auth)
+	methods, err := authMethods(auth)
+	var connection *ssh.Client
+	if err == nil {
+		connection, err = sshDial(auth.User, methods, node.IP.String(), port, auth.Bastion)
+	}
 	if err != nil {
 		util.StopSpinner(fmt.Sprintf("SSH authentication failed for %s", node.IP.String()), logsymbols.Error)
-		if dataSSHUser != "" {
-			err = clearSSHDataForAddr(node.IP.String())
-			if err != nil {
-				return nil, err
+		if auth.source == SourceStore {
+			err2 := clearSSHDataForAddr(node.IP.String())
+			if err2 != nil {
+				return nil, err2
 			}
 		}
 		return nil, err
 	}
-	sshConnections[node.IP.String()] = connection
-	if dataSSHUser == "" && (usedSSHUser != "" || usedSSHPass != "") {
-		err = WriteSSHData(node.IP.String(), usedSSHUser, usedSSHPass, usedPrivateKeyPath)
+	storeConnection(node.IP.String(), connection,
+		&dialInfo{user: auth.User, auth: methods, port: port, bastion: auth.Bastion})
+	if auth.source == SourcePrompt {
+		finalMsg = fmt.Sprintf("SSH connection successful for %s with user \"%s\"", node.IP.String(), auth.User)
+		err = WriteSSHData(node.IP.String(), auth.User, auth.Password, auth.PrivateKeyPath)
 		if err != nil {
 			return nil, err
 		}
 	}
 	util.StopSpinner(finalMsg, logsymbols.Success)
-	node.SSHUser = usedSSHUser
-	node.SSHPass = usedSSHPass
+	node.SSHPort = port
+	node.SSHUser = auth.User
+	node.SSHPass = auth.Password
+	node.SSHPrivateKeyPath = auth.PrivateKeyPath
+	node.SSHKeyPassphrase = auth.Passphrase
+	node.SudoPass = auth.SudoPassword
+	node.Bastion = auth.Bastion
 	Nodes[node.IP.String()] = node
 	return connection, nil
 }
 
-func WriteSSHData(addr string, sshUser string, sshPass string, sshPrivateKeyPath string) error {
-	err := touchFile(sshDataFile)
-	if err != nil {
-		log.Debugf("Error occurred while creating connection data file: %s", sshDataFile)
-		return err
-	}
-	file, _ := os.ReadFile(sshDataFile)
-	file, err = enc.DecryptFile(file)
-	if err != nil {
-		log.Debugf("Error occurred while decrypting connection data file: %s", sshDataFile)
-		return err
-	}
-	data := make(map[string]interface{})
-	err = yaml.Unmarshal(file, &data)
-	if err != nil {
-		log.Debugf("Error occurred while parsing connection data file: %s", sshDataFile)
-		return err
-	}
-	d2 := make(map[string]string)
-	d2["sshUser"] = sshUser
-	d2["sshPass"] = sshPass
-	d2["sshPrivateKeyPath"] = sshPrivateKeyPath
-	data[addr] = d2
-	out, _ := yaml.Marshal(data)
-	out, err = enc.EncryptFile(out)
-	if err != nil {
-		log.Debugf("Error occurred while encrypting connection data file: %s", sshDataFile)
-		return err
-	}
-	err = os.WriteFile(sshDataFile, out, os.FileMode(0666))
-	if err != nil {
-		log.Debugf("Error occurred while writing to connection data file: %s", sshDataFile)
-		return err
-	}
-	return nil
-}
-
-func touchFile(name string) error {
-	folder := name[:strings.LastIndexAny(name, "/")]
-	cmd := exec.Command("bash", "-c", fmt.Sprintf("mkdir -p %s", folder))
-	_, err := cmd.CombinedOutput()
+func askAuth(node *Node) (*HostAuth, error) {
+	var err error
+	auth := &HostAuth{Address: node.IP.String(), source: SourcePrompt}
+	auth.User, err = util.AskString(fmt.Sprintf("Please enter SSH user for %s", node.IP.String()), false,
+		util.CommonValidator)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	file, err := os.OpenFile(name, os.O_CREATE, 0666)
+	var authMethod string
+	authMethod, err = util.AskChoice("Which method want to use for SSH authentication?",
+		[]string{"password", "private-key", "kerberos"})
 	if err != nil {
-		return err
+		return nil, err
+	} else if authMethod == "password" {
+		auth.Password, err = util.AskString(fmt.Sprintf("Please enter SSH pass for %s", node.IP.String()), true,
+			util.PasswordValidator)
+		if err != nil {
+			return nil, err
+		}
+	} else if authMethod == "private-key" {
+		auth.PrivateKeyPath, err = util.AskString(
+			fmt.Sprintf("Please enter SSH private key path for %s", node.IP.String()), false, util.PathValidator)
+		if err != nil {
+			return nil, err
+		}
+	} else if authMethod == "kerberos" {
+		auth.kerberosHost = node.Hostname
+		if auth.kerberosHost == "" {
+			auth.kerberosHost, err = util.AskString(
+				fmt.Sprintf("Please enter host address for %s to use kerberos", node.IP.String()), false,
+				util.CommonValidator)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
-	return file.Close()
+	return auth, nil
 }
 
-func CheckSSHDataForAddr(addr string) (sshUser, sshPass, sshPrivateKeyPath string, err error) {
-	file, _ := os.ReadFile(sshDataFile)
-	file, err = enc.DecryptFile(file)
-	if err != nil {
-		log.Debugf("Error occurred while decrypting connection data file: %s", sshDataFile)
-		return "", "", "", err
-	}
-	data := make(map[string]map[string]string)
-	err = yaml.Unmarshal(file, &data)
-	if err != nil {
-		log.Debugf("Error occurred while parsing connection data file: %s", sshDataFile)
-		return "", "", "", err
+func authMethods(auth *HostAuth) ([]ssh.AuthMethod, error) {
+	if auth.Password != "" {
+		return []ssh.AuthMethod{
+			ssh.Password(auth.Password),
+		}, nil
+	} else if auth.PrivateKeyPath != "" {
+		return getKeyAuth(auth.PrivateKeyPath, auth.Passphrase)
+	} else if auth.kerberosHost != "" {
+		// the client is kept open, it is needed again if the connection is re-established
+		gssapiClient, err := sshkrb5.NewClient()
+		if err != nil {
+			log.Fatalf("GSSAPI client could not created: %v", err)
+		}
+		return []ssh.AuthMethod{
+			ssh.GSSAPIWithMICAuthMethod(gssapiClient, auth.kerberosHost),
+		}, nil
 	}
-	sshUser = data[addr]["sshUser"]
-	sshPass = data[addr]["sshPass"]
-	sshPrivateKeyPath = data[addr]["sshPrivateKeyPath"]
-	return sshUser, sshPass, sshPrivateKeyPath, nil
+	return nil, fmt.Errorf("no SSH password or private key defined for %s", auth.Address)
 }
 
-func clearSSHDataForAddr(addr string) error {
-	file, _ := os.ReadFile(sshDataFile)
-	file, err := enc.DecryptFile(file)
-	if err != nil {
-		log.Debugf("Error occurred while decrypting connection data file: %s", sshDataFile)
-		return err
-	}
-	data := make(map[string]map[string]string)
-	err = yaml.Unmarshal(file, &data)
-	if err != nil {
-		log.Debugf("Error occurred while parsing connection data file: %s", sshDataFile)
-		return err
-	}
-	delete(data, addr)
-	out, _ := yaml.Marshal(data)
-	out, err = enc.EncryptFile(out)
-	if err != nil {
-		log.Debugf("Error occurred while encrypting connection data file: %s", sshDataFile)
-		return err
+func sshPort(node *Node, auth *HostAuth) int {
+	if auth != nil && auth.Port != 0 {
+		return auth.Port
 	}
-	err = os.WriteFile(sshDataFile, out, os.FileMode(0666))
-	if err != nil {
-		log.Debugf("Error occurred while writing to connection data file: %s", sshDataFile)
-		return err
+	if node.SSHPort != 0 {
+		return node.SSHPort
 	}
-	return nil
+	return defaultSSHPort
 }
 
-func getKeyAuth(keyPath string) []ssh.AuthMethod {
+func getKeyAuth(keyPath string, passphrase string) ([]ssh.AuthMethod, error) {
 	pemBytes, err := os.ReadFile(keyPath)
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	// create signer
-	signer, err := ssh.ParsePrivateKey(pemBytes)
+	var signer ssh.Signer
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+	} else {
+		signer, err = ssh.ParsePrivateKey(pemBytes)
+		var missingErr *ssh.PassphraseMissingError
+		if errors.As(err, &missingErr) {
+			passphrase, err = util.AskString(fmt.Sprintf("Please enter passphrase for \"%s\"", keyPath), true,
+				util.PasswordValidator)
+			if err != nil {
+				return nil, err
+			}
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(pemBytes, []byte(passphrase))
+		}
+	}
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	return []ssh.AuthMethod{
 		ssh.PublicKeys(signer),
-	}
+	}, nil
 }
 
-func sshDial(user string, auth []ssh.AuthMethod, addr string, port int) (*ssh.Client, error) {
+func sshDial(user string, auth []ssh.AuthMethod, addr string, port int, bastion string) (*ssh.Client, error) {
 	config := &ssh.ClientConfig{
 		User:            user,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // lgtm[go/insecure-hostkeycallback]
 		Auth:            auth,
+		Timeout:         dialTimeout,
+	}
+	hostPort := net.JoinHostPort(addr, strconv.Itoa(port))
+	var tcpConn net.Conn
+	var err error
+	if bastion != "" {
+		var jump *ssh.Client
+		jump, err = bastionClient(bastion)
+		if err != nil {
+			return nil, fmt.Errorf("bastion \"%s\" connection failed: %w", bastion, err)
+		}
+		tcpConn, err = jump.Dial("tcp", hostPort)
+	} else {
+		dialer := net.Dialer{Timeout: dialTimeout, KeepAlive: tcpKeepAlive}
+		tcpConn, err = dialer.Dial("tcp", hostPort)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c, chans, reqs, err := ssh.NewClientConn(tcpConn, hostPort, config)
+	if err != nil {
+		_ = tcpConn.Close()
+		return nil, err
 	}
-	return ssh.Dial("tcp", net.JoinHostPort(addr, strconv.Itoa(port)), config)
+	return ssh.NewClient(c, chans, reqs), nil
 }
 
 func CloseSSHSessions() {
-	for addr, connection := range sshConnections {
+	for addr, connection := range takeConnections() {
 		err := connection.Close()
 		if err != nil {
 			log.Errorf("Error occurred while closing SSH connection with %s", addr)
@@ -356,13 +315,19 @@ func CloseSSHSessions() {
 }
 
 func SendFile(ip net.IP, srcFile io.Reader, dstPath string) error {
-	exist := sshConnections[ip.String()]
-	if exist == nil {
-		client, err := CreateSshConnection(&Node{IP: ip, SSHPort: 22})
-		if err != nil {
-			return err
-		}
-		exist = client
+	return sendFile(ip, srcFile, dstPath, false)
+}
+
+// SendSecretFile writes srcFile to a new file at dstPath, only the connected user can read it. Permissions are set
+// before anything is written, and an existing file is not reused.
+func SendSecretFile(ip net.IP, srcFile io.Reader, dstPath string) error {
+	return sendFile(ip, srcFile, dstPath, true)
+}
+
+func sendFile(ip net.IP, srcFile io.Reader, dstPath string, secret bool) error {
+	exist, err := CreateSshConnection(&Node{IP: ip, SSHPort: 22})
+	if err != nil {
+		return err
 	}
 	sftpClient, err := sftp.NewClient(exist)
 	if err != nil {
@@ -376,7 +341,12 @@ func SendFile(ip net.IP, srcFile io.Reader, dstPath string) error {
 	}()
 
 	// Create the destination file
-	dstFile, err := sftpClient.Create(dstPath)
+	var dstFile *sftp.File
+	if secret {
+		dstFile, err = sftpClient.OpenFile(dstPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	} else {
+		dstFile, err = sftpClient.Create(dstPath)
+	}
 	if err != nil {
 		return err
 	}
@@ -387,6 +357,11 @@ func SendFile(ip net.IP, srcFile io.Reader, dstPath string) error {
 		}
 	}()
 
+	if secret {
+		if err = dstFile.Chmod(0600); err != nil {
+			return err
+		}
+	}
 	// write to file
 	if _, err = dstFile.ReadFrom(srcFile); err != nil {
 		return err
@@ -0,0 +1,196 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"com.github.tunahansezen/tkube/pkg/constant"
+	enc "com.github.tunahansezen/tkube/pkg/encryption"
+	"com.github.tunahansezen/tkube/pkg/util"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	storePassphrase string
+)
+
+type SSHData struct {
+	Addr              string
+	SSHUser           string
+	SSHPass           string
+	SSHPrivateKeyPath string
+}
+
+func WriteSSHData(addr string, sshUser string, sshPass string, sshPrivateKeyPath string) error {
+	data, err := readSSHData()
+	if err != nil {
+		return err
+	}
+	d2 := make(map[string]string)
+	d2["sshUser"] = sshUser
+	d2["sshPass"] = sshPass
+	d2["sshPrivateKeyPath"] = sshPrivateKeyPath
+	data[addr] = d2
+	return writeSSHData(data)
+}
+
+func CheckSSHDataForAddr(addr string) (sshUser, sshPass, sshPrivateKeyPath string, err error) {
+	data, err := readSSHData()
+	if err != nil {
+		return "", "", "", err
+	}
+	sshUser = data[addr]["sshUser"]
+	sshPass = data[addr]["sshPass"]
+	sshPrivateKeyPath = data[addr]["sshPrivateKeyPath"]
+	return sshUser, sshPass, sshPrivateKeyPath, nil
+}
+
+func ListSSHData() ([]SSHData, error) {
+	data, err := readSSHData()
+	if err != nil {
+		return nil, err
+	}
+	var list []SSHData
+	for addr, d := range data {
+		list = append(list, SSHData{Addr: addr, SSHUser: d["sshUser"], SSHPass: d["sshPass"],
+			SSHPrivateKeyPath: d["sshPrivateKeyPath"]})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Addr < list[j].Addr
+	})
+	return list, nil
+}
+
+func ForgetSSHData(addr string) (bool, error) {
+	data, err := readSSHData()
+	if err != nil {
+		return false, err
+	}
+	if _, exists := data[addr]; !exists {
+		return false, nil
+	}
+	delete(data, addr)
+	return true, writeSSHData(data)
+}
+
+func clearSSHDataForAddr(addr string) error {
+	_, err := ForgetSSHData(addr)
+	return err
+}
+
+// SetSSHDataFile sets the credential store file, every cluster has its own one.
+func SetSSHDataFile(path string) {
+	sshDataFile = path
+}
+
+func readSSHData() (map[string]map[string]string, error) {
+	data := make(map[string]map[string]string)
+	if sshDataFile == "" {
+		return nil, errors.New("credential store is not set")
+	}
+	file, err := os.ReadFile(sshDataFile)
+	if errors.Is(err, os.ErrNotExist) || len(file) == 0 {
+		return data, nil
+	} else if err != nil {
+		log.Debugf("Error occurred while reading connection data file: %s", sshDataFile)
+		return nil, err
+	}
+	var plainText []byte
+	legacy := !enc.IsEncrypted(file)
+	if legacy {
+		plainText, err = enc.DecryptLegacy(file)
+	} else {
+		var passphrase string
+		passphrase, err = getStorePassphrase(false)
+		if err != nil {
+			return nil, err
+		}
+		plainText, err = enc.Decrypt(file, passphrase)
+		if err != nil {
+			storePassphrase = ""
+		}
+	}
+	if err != nil {
+		log.Debugf("Error occurred while decrypting connection data file: %s", sshDataFile)
+		return nil, err
+	}
+	err = yaml.Unmarshal(plainText, &data)
+	if err != nil {
+		log.Debugf("Error occurred while parsing connection data file: %s", sshDataFile)
+		return nil, err
+	}
+	if data == nil {
+		data = make(map[string]map[string]string)
+	}
+	if legacy {
+		fmt.Printf("Migrating credential store \"%s\" to passphrase based encryption\n", sshDataFile)
+		err = writeSSHData(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func writeSSHData(data map[string]map[string]string) error {
+	folder := sshDataFile[:strings.LastIndexAny(sshDataFile, "/")]
+	err := os.MkdirAll(folder, os.FileMode(0700))
+	if err != nil {
+		log.Debugf("Error occurred while creating connection data folder: %s", folder)
+		return err
+	}
+	file, _ := os.ReadFile(sshDataFile)
+	passphrase, err := getStorePassphrase(!enc.IsEncrypted(file))
+	if err != nil {
+		return err
+	}
+	out, _ := yaml.Marshal(data)
+	out, err = enc.Encrypt(out, passphrase)
+	if err != nil {
+		log.Debugf("Error occurred while encrypting connection data file: %s", sshDataFile)
+		return err
+	}
+	err = os.WriteFile(sshDataFile, out, os.FileMode(0600))
+	if err != nil {
+		log.Debugf("Error occurred while writing to connection data file: %s", sshDataFile)
+		return err
+	}
+	// files created by older versions are world-readable
+	return os.Chmod(sshDataFile, os.FileMode(0600))
+}
+
+func getStorePassphrase(newStore bool) (string, error) {
+	if storePassphrase != "" {
+		return storePassphrase, nil
+	}
+	if envPass := os.Getenv(constant.CredentialStorePassphraseEnv); envPass != "" {
+		storePassphrase = envPass
+		return storePassphrase, nil
+	}
+	if !newStore {
+		passphrase, err := util.AskString("Please enter credential store passphrase", true, util.PasswordValidator)
+		if err != nil {
+			return "", err
+		}
+		storePassphrase = passphrase
+		return storePassphrase, nil
+	}
+	passphrase, err := util.AskString("Please define a passphrase for credential store", true,
+		util.PasswordValidator)
+	if err != nil {
+		return "", err
+	}
+	confirmation, err := util.AskString("Please confirm credential store passphrase", true, util.PasswordValidator)
+	if err != nil {
+		return "", err
+	}
+	if passphrase != confirmation {
+		return "", errors.New("credential store passphrases do not match")
+	}
+	storePassphrase = passphrase
+	return storePassphrase, nil
+}
@@ -0,0 +1,212 @@
+package connection
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+	"gopkg.in/yaml.v3"
+)
+
+// Credential sources, in order of precedence. A source with higher precedence always wins for the same address.
+const (
+	SourceAuthFile   = "auth-file"
+	SourceDeployment = "deployment"
+	SourceStore      = "credential-store"
+	SourcePrompt     = "prompt"
+)
+
+var (
+	fileAuths       = make(map[string]*HostAuth)
+	deploymentAuths = make(map[string]*HostAuth)
+)
+
+// Inventory is the content of the file given with --auth-file. It can be written in YAML or JSON.
+type Inventory struct {
+	Defaults HostAuth   `yaml:"defaults" json:"defaults"`
+	Hosts    []HostAuth `yaml:"hosts" json:"hosts"`
+}
+
+type HostAuth struct {
+	Address             string `yaml:"address" json:"address"`
+	User                string `yaml:"user" json:"user"`
+	Password            string `yaml:"password" json:"password"`
+	PasswordFromEnv     string `yaml:"passwordFromEnv" json:"passwordFromEnv"`
+	PrivateKeyPath      string `yaml:"privateKeyPath" json:"privateKeyPath"`
+	Passphrase          string `yaml:"passphrase" json:"passphrase"`
+	PassphraseFromEnv   string `yaml:"passphraseFromEnv" json:"passphraseFromEnv"`
+	SudoPassword        string `yaml:"sudoPassword" json:"sudoPassword"`
+	SudoPasswordFromEnv string `yaml:"sudoPasswordFromEnv" json:"sudoPasswordFromEnv"`
+	Bastion             string `yaml:"bastion" json:"bastion"`
+	Port                int    `yaml:"port" json:"port"`
+	source              string
+	kerberosHost        string
+}
+
+// withDefaults fills empty fields of a from d.
+func (a HostAuth) withDefaults(d HostAuth) HostAuth {
+	if a.User == "" {
+		a.User = d.User
+	}
+	if a.Password == "" && a.PasswordFromEnv == "" && a.PrivateKeyPath == "" {
+		a.Password = d.Password
+		a.PasswordFromEnv = d.PasswordFromEnv
+		a.PrivateKeyPath = d.PrivateKeyPath
+		a.Passphrase = d.Passphrase
+		a.PassphraseFromEnv = d.PassphraseFromEnv
+	}
+	if a.SudoPassword == "" && a.SudoPasswordFromEnv == "" {
+		a.SudoPassword = d.SudoPassword
+		a.SudoPasswordFromEnv = d.SudoPasswordFromEnv
+	}
+	if a.Bastion == "" {
+		a.Bastion = d.Bastion
+	}
+	if a.Port == 0 {
+		a.Port = d.Port
+	}
+	return a
+}
+
+func (a *HostAuth) resolveEnv() error {
+	var err error
+	if a.Password, err = fromEnv(a.Password, a.PasswordFromEnv); err != nil {
+		return err
+	}
+	if a.Passphrase, err = fromEnv(a.Passphrase, a.PassphraseFromEnv); err != nil {
+		return err
+	}
+	a.SudoPassword, err = fromEnv(a.SudoPassword, a.SudoPasswordFromEnv)
+	return err
+}
+
+func fromEnv(value string, env string) (string, error) {
+	if env == "" {
+		return value, nil
+	}
+	envValue, exists := os.LookupEnv(env)
+	if !exists {
+		return "", fmt.Errorf("environment variable \"%s\" is not set", env)
+	}
+	return envValue, nil
+}
+
+// LoadAuthFile reads the credential inventory at path. Entries of the inventory take precedence over
+// deployment config and credential store entries of the same address.
+func LoadAuthFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var inventory Inventory
+	// JSON is valid YAML, so both formats are read by the YAML decoder
+	err = yaml.Unmarshal(data, &inventory)
+	if err != nil {
+		return fmt.Errorf("auth file \"%s\" could not be parsed: %w", path, err)
+	}
+	for i, host := range inventory.Hosts {
+		if host.Address == "" {
+			return fmt.Errorf("auth file \"%s\": address is missing for %d. host", path, i+1)
+		}
+		auth := host.withDefaults(inventory.Defaults)
+		err = auth.resolveEnv()
+		if err != nil {
+			return fmt.Errorf("auth file \"%s\": %s: %w", path, host.Address, err)
+		}
+		if auth.User == "" {
+			return fmt.Errorf("auth file \"%s\": user is missing for %s", path, host.Address)
+		}
+		auth.source = SourceAuthFile
+		fileAuths[host.Address] = &auth
+	}
+	log.Debugf("%d host(s) read from auth file: %s", len(inventory.Hosts), path)
+	return nil
+}
+
+// AddAuthMapEntry registers a host read from the deprecated --auth-map flag. It is treated like an auth file
+// entry but never overrides one.
+func AddAuthMapEntry(addr string, user string, pass string, privateKeyPath string) {
+	if _, exists := fileAuths[addr]; exists {
+		return
+	}
+	fileAuths[addr] = &HostAuth{Address: addr, User: user, Password: pass, PrivateKeyPath: privateKeyPath,
+		source: SourceAuthFile}
+}
+
+// AddDeploymentAuth registers credentials defined for a node in deployment config. They are only kept in memory.
+func AddDeploymentAuth(addr string, user string, pass string, privateKeyPath string) {
+	if user == "" || (pass == "" && privateKeyPath == "") {
+		return
+	}
+	deploymentAuths[addr] = &HostAuth{Address: addr, User: user, Password: pass, PrivateKeyPath: privateKeyPath,
+		source: SourceDeployment}
+}
+
+// lookupAuth returns the credentials of addr with the highest precedence:
+// auth file > deployment config > credential store. It returns nil if the user needs to be asked.
+func lookupAuth(node *Node) (*HostAuth, error) {
+	addr := node.IP.String()
+	if auth := fileAuthOf(node); auth != nil {
+		return auth, nil
+	}
+	if auth := deploymentAuths[addr]; auth != nil {
+		return auth, nil
+	}
+	if node.SSHUser != "" && (node.SSHPass != "" || node.SSHPrivateKeyPath != "") {
+		return &HostAuth{Address: addr, User: node.SSHUser, Password: node.SSHPass,
+			PrivateKeyPath: node.SSHPrivateKeyPath, source: SourceDeployment}, nil
+	}
+	dataSSHUser, dataSSHPass, dataSSHPrivateKey, err := CheckSSHDataForAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if dataSSHUser != "" && (dataSSHPass != "" || dataSSHPrivateKey != "") {
+		return &HostAuth{Address: addr, User: dataSSHUser, Password: dataSSHPass, PrivateKeyPath: dataSSHPrivateKey,
+			source: SourceStore}, nil
+	}
+	return nil, nil
+}
+
+func fileAuthOf(node *Node) *HostAuth {
+	if auth := fileAuths[node.IP.String()]; auth != nil {
+		return auth
+	}
+	if node.Hostname != "" {
+		return fileAuths[node.Hostname]
+	}
+	return nil
+}
+
+// bastionClient returns the connection to the bastion given as "host" or "host:port".
+func bastionClient(bastion string) (*ssh.Client, error) {
+	host, portStr, err := net.SplitHostPort(bastion)
+	if err != nil {
+		host = strings.Trim(bastion, "[]")
+		portStr = ""
+	}
+	port := 0
+	if portStr != "" {
+		port, err = strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("bastion \"%s\" has invalid port", bastion)
+		}
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ips, err := net.LookupIP(host)
+		if err != nil || len(ips) == 0 {
+			return nil, fmt.Errorf("bastion \"%s\" could not be resolved", bastion)
+		}
+		ip = ips[0]
+	}
+	node := &Node{IP: ip, SSHPort: port, Hostname: host}
+	if auth := fileAuthOf(node); auth != nil && auth.Bastion != "" {
+		return nil, errors.New("nested bastions are not supported")
+	}
+	return CreateSshConnection(node)
+}
@@ -0,0 +1,209 @@
+package connection
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	dialTimeout       = 15 * time.Second
+	tcpKeepAlive      = 30 * time.Second
+	keepAliveInterval = 15 * time.Second
+	keepAliveTimeout  = 10 * time.Second
+	reconnectAttempts = 5
+	reconnectWait     = 5 * time.Second
+	rebootPollWait    = 5 * time.Second
+)
+
+var (
+	connMutex      sync.Mutex
+	sshConnections = make(map[string]*ssh.Client)
+	dialInfos      = make(map[string]*dialInfo)
+)
+
+// dialInfo keeps what is needed to open a new connection to an already authenticated node without asking again.
+type dialInfo struct {
+	user    string
+	auth    []ssh.AuthMethod
+	port    int
+	bastion string
+}
+
+func getConnection(addr string) *ssh.Client {
+	connMutex.Lock()
+	defer connMutex.Unlock()
+	return sshConnections[addr]
+}
+
+func storeConnection(addr string, client *ssh.Client, info *dialInfo) {
+	connMutex.Lock()
+	sshConnections[addr] = client
+	if info != nil {
+		dialInfos[addr] = info
+	}
+	connMutex.Unlock()
+	go keepAlive(addr, client)
+}
+
+// dropConnection closes and forgets the client of addr if it is still the cached one.
+func dropConnection(addr string, client *ssh.Client) {
+	connMutex.Lock()
+	if client == nil || sshConnections[addr] == client {
+		client = sshConnections[addr]
+		delete(sshConnections, addr)
+	}
+	connMutex.Unlock()
+	if client != nil {
+		_ = client.Close()
+	}
+}
+
+func takeConnections() map[string]*ssh.Client {
+	connMutex.Lock()
+	defer connMutex.Unlock()
+	connections := sshConnections
+	sshConnections = make(map[string]*ssh.Client)
+	return connections
+}
+
+// keepAlive sends keepalive requests until the client is closed. A client which does not answer in time is
+// dropped, so the next call reconnects instead of failing on a dead connection.
+func keepAlive(addr string, client *ssh.Client) {
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			dropConnection(addr, client)
+			return
+		case <-ticker.C:
+			if !isAlive(client) {
+				log.Debugf("SSH connection with %s is broken", addr)
+				dropConnection(addr, client)
+				return
+			}
+		}
+	}
+}
+
+func isAlive(client *ssh.Client) bool {
+	result := make(chan error, 1)
+	go func() {
+		_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+		result <- err
+	}()
+	select {
+	case err := <-result:
+		return err == nil
+	case <-time.After(keepAliveTimeout):
+		return false
+	}
+}
+
+// reconnect opens a new connection for a previously authenticated address. It returns nil client and nil error
+// if the address was never connected before.
+func reconnect(addr string) (*ssh.Client, error) {
+	connMutex.Lock()
+	info := dialInfos[addr]
+	connMutex.Unlock()
+	if info == nil {
+		return nil, nil
+	}
+	var err error
+	for attempt := 1; attempt <= reconnectAttempts; attempt++ {
+		var client *ssh.Client
+		client, err = sshDial(info.user, info.auth, addr, info.port, info.bastion)
+		if err == nil {
+			log.Debugf("Reconnected to %s", addr)
+			storeConnection(addr, client, nil)
+			return client, nil
+		}
+		log.Debugf("Reconnect attempt %d to %s failed: %s", attempt, addr, err.Error())
+		time.Sleep(reconnectWait)
+	}
+	return nil, fmt.Errorf("SSH connection with %s could not be re-established: %w", addr, err)
+}
+
+// Reconnect drops the cached connection of ip and opens a new one.
+func Reconnect(ip net.IP) (*ssh.Client, error) {
+	dropConnection(ip.String(), nil)
+	client, err := reconnect(ip.String())
+	if client == nil && err == nil {
+		return CreateSshConnection(&Node{IP: ip, SSHPort: 22})
+	}
+	return client, err
+}
+
+// IsConnectionError reports whether err is caused by a lost connection rather than a failed command.
+func IsConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var exitErr *ssh.ExitError
+	return !errors.As(err, &exitErr)
+}
+
+func GetBootID(ip net.IP) (string, error) {
+	client, err := CreateSshConnection(&Node{IP: ip, SSHPort: 22})
+	if err != nil {
+		return "", err
+	}
+	return runOutput(client, "cat /proc/sys/kernel/random/boot_id")
+}
+
+// WaitForNodeReboot waits until the node comes back with a boot id different from prevBootID and re-establishes
+// the SSH connection.
+func WaitForNodeReboot(ip net.IP, prevBootID string, timeout time.Duration) error {
+	addr := ip.String()
+	dropConnection(addr, nil)
+	connMutex.Lock()
+	info := dialInfos[addr]
+	connMutex.Unlock()
+	if info == nil {
+		return fmt.Errorf("no previous SSH connection found for %s", addr)
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(rebootPollWait)
+		if info.bastion == "" && !IsReachable(addr, info.port) {
+			continue
+		}
+		client, err := sshDial(info.user, info.auth, addr, info.port, info.bastion)
+		if err != nil {
+			continue
+		}
+		bootID, err := runOutput(client, "cat /proc/sys/kernel/random/boot_id")
+		if err != nil || bootID == prevBootID {
+			_ = client.Close()
+			continue
+		}
+		storeConnection(addr, client, nil)
+		return nil
+	}
+	return fmt.Errorf("%s did not come back in %s after reboot", addr, timeout)
+}
+
+func runOutput(client *ssh.Client, cmd string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", err
+	}
+	defer session.Close()
+	var bs bytes.Buffer
+	session.Stdout = &bs
+	err = session.Run(cmd)
+	return strings.TrimSpace(bs.String()), err
+}
@@ -13,6 +13,16 @@ const (
 	EtcdClientKeyPath             = "/etc/etcd/pki/apiserver-etcd-client.key"
 	EtcdClientCertPath            = "/etc/etcd/pki/apiserver-etcd-client.crt"
 	EtcdRecoveryCertFolder        = "recovery/etcd-certs"
+	ClustersFolder                = "clusters"
+	StateFolder                   = "state"
+	PkiFolder                     = "pki"
+	LogsFolder                    = "logs"
+	CredentialsFile               = "credentials"
+	LegacyCredentialsFile         = "data/ssh"
+	CurrentClusterFile            = "current-cluster"
+	DefaultClusterName            = "default"
+	KubeadmCfgFile                = "kubeadm-config.yaml"
+	KubeadmJoinCfgFile            = "kubeadm-join.yaml"
 	DeploymentCfgName             = "deployment"
 	DefaultCfgType                = "yaml"
 	DefaultContainerdSandboxImage = "pause:3.9"
@@ -26,8 +36,12 @@ const (
 	DefaultKubeYumRepoAddress     = "https://pkgs.k8s.io/core:/stable:/v{version}/rpm/"
 	DefaultKubeYumRepoKey         = "https://pkgs.k8s.io/core:/stable:/v{version}/rpm/repodata/repomd.xml.key"
 	DefaultPodSubnet              = "10.244.0.0/16"
+	ApiServerPort                 = 6443
+	LoadBalancerHAProxy           = "haproxy"
+	DefaultLoadBalancerPort       = 8443
 	DefaultKubeImageRegistry      = "registry.k8s.io"
 	DefaultCalicoUrl              = "default"
 	DefaultHelmUrl                = "default"
 	DefaultHelmfileUrl            = "default"
+	CredentialStorePassphraseEnv  = "TKUBE_CREDENTIALS_PASSPHRASE"
 )
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+
+	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/config/templates"
+	"com.github.tunahansezen/tkube/pkg/constant"
+	"com.github.tunahansezen/tkube/pkg/kube"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/path"
+	"com.github.tunahansezen/tkube/pkg/util"
+	"github.com/guumaster/logsymbols"
+)
+
+// writeAuditFiles writes the audit policy and webhook kubeconfig to the master, apiserver reads them on start.
+func writeAuditFiles(masterNode model.KubeNode) {
+	audit := cfg.DeploymentCfg.Kubernetes.Audit
+	if !audit.Enabled {
+		return
+	}
+	policy := audit.Policy
+	if policy == "" {
+		var err error
+		policy, err = util.RenderTemplate(templates.AuditPolicyYaml, util.TemplateVars{"Preset": audit.GetPreset()})
+		os.ThrowIfError(err, 1)
+	}
+	os.RunCommandOn(fmt.Sprintf("sudo mkdir -p %s", model.AuditDir), masterNode.IP, true)
+	os.CreateFile([]byte(policy), model.AuditPolicyFile, masterNode.IP)
+	if audit.Webhook != nil {
+		os.CreateSecretFile([]byte(audit.Webhook.KubeConfig), model.AuditWebhookFile, masterNode.IP)
+	}
+}
+
+// ApplyAudit rolls out audit settings master by master. The apiserver manifest of a master is regenerated by kubeadm
+// and the next master is started after its apiserver is running again. Apiserver is restarted if its manifest does
+// not change, it reads the policy only on start.
+func ApplyAudit() {
+	masters := cfg.DeploymentCfg.GetMasterKubeNodes()
+	multiMaster := len(masters) > 1
+	for i, masterNode := range masters {
+		util.StartSpinner(fmt.Sprintf("Applying audit config on \"%s\"", masterNode.Hostname))
+		writeAuditFiles(masterNode)
+		kubeadmCfgFile := fmt.Sprintf("%s/%s", path.GetTKubeTmpDir(masterNode.IP), constant.KubeadmCfgFile)
+		certKey := ""
+		if i == 0 {
+			// kubeadm config kept on the first master is updated as well
+			kubeadmCfgFile = path.GetKubeadmCfgFile()
+			certKey = kube.CreateCertKey(KubeVersion, masterNode.IP)
+		}
+		os.CreateFile(kube.CreateCombinedKubeadmCfg(KubeVersion,
+			cfg.DeploymentCfg.GetControlPlaneEndpoint(masters[0].IP), masterNode, certKey, cfg.DeploymentCfg,
+			multiMaster), kubeadmCfgFile, masterNode.IP)
+		startedAt := kube.ApiServerStartedAt(masterNode.Hostname)
+		manifestHash := os.RunCommandOn(fmt.Sprintf("sudo sha256sum %s", apiServerManifest), masterNode.IP, true)
+		patches := kube.NodePatches(cfg.DeploymentCfg, masterNode)
+		kube.WritePatches(masterNode.IP, patches)
+		os.RunCommandOn(fmt.Sprintf("sudo kubeadm init phase control-plane apiserver --config %s%s", kubeadmCfgFile,
+			kube.PatchesFlag(KubeVersion, patches)), masterNode.IP, true)
+		if os.RunCommandOn(fmt.Sprintf("sudo sha256sum %s", apiServerManifest), masterNode.IP, true) ==
+			manifestHash {
+			restartApiServer(masterNode)
+		} else {
+			util.UpdateSpinner(fmt.Sprintf("Waiting apiserver on \"%s\"", masterNode.Hostname))
+			kube.WaitUntilApiServerRestarted(masterNode.Hostname, startedAt)
+		}
+		if i == 0 {
+			os.RunCommandOn(fmt.Sprintf("sudo kubeadm init phase upload-config kubeadm --config %s", kubeadmCfgFile),
+				masterNode.IP, true)
+		} else {
+			os.RunCommandOn(fmt.Sprintf("rm -f %s", kubeadmCfgFile), masterNode.IP, true)
+		}
+		util.StopSpinner("", logsymbols.Success)
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+
+	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/config/compat"
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/constant"
+	"com.github.tunahansezen/tkube/pkg/kube"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/path"
+	"com.github.tunahansezen/tkube/pkg/util"
+	"github.com/guumaster/logsymbols"
+)
+
+// applyCNI installs the network plugin of deployment config with kubectl on node. It is applied again when nodes are
+// added, so everything it applies must be safe to apply twice.
+func applyCNI(node model.KubeNode) {
+	switch cfg.DeploymentCfg.Kubernetes.CNI.GetPlugin() {
+	case model.CNIPluginCalico:
+		applyCalico(node)
+	case model.CNIPluginFlannel:
+		applyFlannel(node)
+	case model.CNIPluginNone:
+		applyCNIHooks(node)
+	}
+}
+
+func applyCalico(node model.KubeNode) {
+	if cfg.DeploymentCfg.Kubernetes.Calico.GetMode() == model.CalicoModeOperator {
+		applyCalicoOperator(node)
+		return
+	}
+	util.StartSpinner(fmt.Sprintf("Applying calico config \"%s\" with version", getCalicoVersion()))
+	manifestFile := fetchManifest("calico.yaml", fmt.Sprintf("calico/calico-%s.yaml", CalicoVersion),
+		cfg.DeploymentCfg.GetCalicoExactUrl(getCalicoVersion()), node.IP)
+	data, err := os.ReadFile(manifestFile, node.IP)
+	os.ThrowIfError(err, 1)
+	data, err = kube.PatchCalicoManifest(data, cfg.DeploymentCfg)
+	os.ThrowIfError(err, 1)
+	os.CreateFile(data, manifestFile, node.IP)
+	os.RunCommandOn(fmt.Sprintf("kubectl apply -f %s", manifestFile), node.IP, true)
+	util.StopSpinner("", logsymbols.Success)
+}
+
+// applyCalicoOperator installs the tigera operator, which installs calico from the Installation resource.
+func applyCalicoOperator(node model.KubeNode) {
+	util.StartSpinner(fmt.Sprintf("Applying calico operator with version \"%s\"", getCalicoVersion()))
+	manifestFile := fetchManifest("tigera-operator.yaml", "",
+		cfg.DeploymentCfg.GetCalicoExactUrl(getCalicoVersion()), node.IP)
+	data, err := os.ReadFile(manifestFile, node.IP)
+	os.ThrowIfError(err, 1)
+	data, err = kube.PatchTigeraOperatorManifest(data, cfg.DeploymentCfg)
+	os.ThrowIfError(err, 1)
+	os.CreateFile(data, manifestFile, node.IP)
+	// operator CRDs are too large for the last applied annotation of client side apply
+	os.RunCommandOn(fmt.Sprintf("kubectl apply --server-side --force-conflicts -f %s", manifestFile),
+		node.IP, true)
+	os.RunCommandOn("kubectl wait --for condition=established --timeout=120s crd/installations.operator.tigera.io",
+		node.IP, true)
+	installationFile := fmt.Sprintf("%s/calico-installation.yaml", path.GetTKubeTmpDir(node.IP))
+	os.CreateFile(kube.CreateCalicoInstallation(cfg.DeploymentCfg), installationFile, node.IP)
+	os.RunCommandOn(fmt.Sprintf("kubectl apply --server-side --force-conflicts -f %s", installationFile),
+		node.IP, true)
+	util.StopSpinner("", logsymbols.Success)
+}
+
+func applyFlannel(node model.KubeNode) {
+	util.StartSpinner(fmt.Sprintf("Applying flannel config with version \"%s\"", getFlannelVersion()))
+	manifestFile := fetchManifest("kube-flannel.yml", fmt.Sprintf("flannel/kube-flannel-%s.yml", FlannelVersion),
+		cfg.DeploymentCfg.GetFlannelExactUrl(getFlannelVersion()), node.IP)
+	data, err := os.ReadFile(manifestFile, node.IP)
+	os.ThrowIfError(err, 1)
+	settings := kube.FlannelSettings{
+		Network:     cfg.DeploymentCfg.GetPodCIDR(false),
+		IPv6Network: cfg.DeploymentCfg.GetPodCIDR(true),
+		Backend:     cfg.DeploymentCfg.Kubernetes.CNI.Flannel.GetBackend(),
+		Interfaces:  cfg.DeploymentCfg.GetNodeInterfaces(),
+	}
+	settings.InterfaceShared = cfg.DeploymentCfg.GetSharedNodeInterface() != ""
+	if cfg.DeploymentCfg.Kubernetes.ImageRegistry != constant.DefaultKubeImageRegistry {
+		settings.ImageRegistry = cfg.DeploymentCfg.Kubernetes.ImageRegistry
+	}
+	data, err = kube.PatchFlannelManifest(data, settings)
+	os.ThrowIfError(err, 1)
+	os.CreateFile(data, manifestFile, node.IP)
+	os.RunCommandOn(fmt.Sprintf("kubectl apply -f %s", manifestFile), node.IP, true)
+	util.StopSpinner("", logsymbols.Success)
+}
+
+// applyCNIHooks installs the network plugin from manifests and chart of deployment config. Nodes stay NotReady
+// without any of them, until a network plugin is installed after tkube.
+func applyCNIHooks(node model.KubeNode) {
+	cni := cfg.DeploymentCfg.Kubernetes.CNI
+	if !cni.HasHooks() {
+		util.PrintWarning("No network plugin is installed, nodes are not ready until one is installed")
+		return
+	}
+	hookDir := fmt.Sprintf("%s/cni", path.GetTKubeTmpDir(node.IP))
+	os.RunCommandOn(fmt.Sprintf("rm -rf %s && mkdir -p %s", hookDir, hookDir), node.IP, true)
+	for i, manifest := range cni.Manifests {
+		util.StartSpinner(fmt.Sprintf("Applying cni manifest \"%s\"", manifest))
+		manifestFile := copyHookFile(manifest, fmt.Sprintf("%s/%d-%s", hookDir, i, filepath.Base(manifest)),
+			node.IP)
+		os.RunCommandOn(fmt.Sprintf("kubectl apply -f %s", manifestFile), node.IP, true)
+		util.StopSpinner("", logsymbols.Success)
+	}
+	if cni.Helm != nil {
+		util.StartSpinner(fmt.Sprintf("Installing cni chart \"%s\"", cni.Helm.Chart))
+		chartFile := copyHookFile(cni.Helm.Chart, fmt.Sprintf("%s/%s", hookDir, filepath.Base(cni.Helm.Chart)),
+			node.IP)
+		valuesFlags := ""
+		for i, values := range cni.Helm.Values {
+			valuesFlags += fmt.Sprintf(" -f %s", copyHookFile(values, fmt.Sprintf("%s/values-%d.yaml", hookDir, i),
+				node.IP))
+		}
+		os.RunCommandOn(fmt.Sprintf("helm upgrade --install %s %s -n %s --create-namespace%s", cni.Helm.Release,
+			chartFile, cni.Helm.GetNamespace(), valuesFlags), node.IP, true)
+		util.StopSpinner("", logsymbols.Success)
+	}
+}
+
+// copyHookFile copies a file on the machine running tkube to dstFile on the node.
+func copyHookFile(srcFile, dstFile string, ip net.IP) string {
+	data, err := os.ReadFile(srcFile, nil)
+	if err != nil {
+		os.Exit(fmt.Sprintf("Error occurred while reading \"%s\": %s", srcFile, err.Error()), 1)
+	}
+	os.CreateFile(data, dstFile, ip)
+	return dstFile
+}
+
+// fetchManifest copies the manifest from the iso in offline installation, downloads it from url otherwise. Path of
+// the manifest in tkube tmp dir is returned.
+func fetchManifest(name, isoFile, url string, ip net.IP) string {
+	tmpDir := path.GetTKubeTmpDir(ip)
+	manifestFile := fmt.Sprintf("%s/%s", tmpDir, name)
+	os.RunCommandOn(fmt.Sprintf("mkdir -p %s && rm -f %s", tmpDir, manifestFile), ip, true)
+	if IsoPath != "" {
+		os.RunCommandOn(fmt.Sprintf("sudo cp %s/%s %s && sudo chown $(id -u):$(id -g) %s", constant.IsoMountDir,
+			isoFile, manifestFile, manifestFile), ip, true)
+	} else {
+		os.RunCommandOn(fmt.Sprintf("wget -nc -qO %s %s --no-check-certificate", manifestFile, url), ip, true)
+	}
+	return manifestFile
+}
+
+func getFlannelVersion() string {
+	if FlannelVersion != "auto" {
+		return FlannelVersion
+	}
+	release, _ := compat.Lookup(KubeVersion)
+	return release.Flannel
+}
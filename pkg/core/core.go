@@ -2,8 +2,10 @@ package core
 
 import (
 	"fmt"
+	"strings"
 
 	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/config/compat"
 	"com.github.tunahansezen/tkube/pkg/config/model"
 	conn "com.github.tunahansezen/tkube/pkg/connection"
 	"com.github.tunahansezen/tkube/pkg/constant"
@@ -16,6 +18,12 @@ import (
 )
 
 func PreRun() {
+	PreRunCluster()
+	loadCredentials()
+	err := os.ValidatePrivilegeEscalation()
+	if err != nil {
+		os.Exit(err.Error(), 1)
+	}
 	os.RemoteNode = &conn.Node{IP: os.RemoteNodeIP}
 	if IsoPath != "" {
 		if os.RemoteNodeIP != nil {
@@ -24,7 +32,7 @@ func PreRun() {
 				os.Exit(err.Error(), 1)
 			}
 		}
-		os.AddToSudoers(os.RemoteNode.IP)
+		os.PreparePrivilegeEscalation(os.RemoteNode.IP)
 		util.StartSpinner(fmt.Sprintf("Umounting previous iso dir \"%s\" if exists on \"%s\"", constant.IsoMountDir, os.RemoteNode.IP))
 		os.UmountISO(constant.IsoMountDir, os.RemoteNode.IP)
 		util.StopSpinner("", logsymbols.Success)
@@ -39,37 +47,81 @@ func PreRun() {
 		if err != nil {
 			os.Exit(err.Error(), 1)
 		}
+		if versionOverrides[ComponentKubernetes] && !kubeVersionMatches(KubeVersion, isoVersions.Kubernetes) {
+			os.Exit(fmt.Sprintf("Kubernetes version \"%s\" does not match \"%s\" in iso file", KubeVersion,
+				isoVersions.Kubernetes), 1)
+		}
 		KubeVersion = isoVersions.Kubernetes
 		DockerVersion = isoVersions.Docker
 		CalicoVersion = isoVersions.Calico
 		EtcdVersion = isoVersions.Etcd
 		HelmVersion = isoVersions.Helm
 		HelmfileVersion = isoVersions.Helmfile
+		KubeVipIsoVersion = isoVersions.KubeVip
+		if isoVersions.Flannel != "" {
+			FlannelVersion = isoVersions.Flannel
+			SetVersionOverride(ComponentFlannel)
+		}
+		for _, component := range []string{ComponentKubernetes, ComponentDocker, ComponentCalico, ComponentEtcd,
+			ComponentHelm, ComponentHelmfile} {
+			SetVersionOverride(component)
+		}
 	}
-	helmSemVer, _ := version.NewVersion(HelmVersion)
-	minHelmVer, _ := version.NewVersion("3.0.0")
-	if helmSemVer.LessThan(minHelmVer) {
-		os.Exit(fmt.Sprintf("Minimum supported helm version is  \"%s\"", minHelmVer), 1)
+	os.DetectOS()
+	path.CalculatePaths()
+	cfg.ReadConfig()
+	resolveKubeVersion()
+	resolveVersions()
+	resolveAutoVersions()
+	warnings, err := compat.Validate(compat.Versions{
+		Kubernetes: KubeVersion,
+		Helm:       HelmVersion,
+		Etcd:       EtcdVersion,
+		Containerd: ContainerdVersion,
+		Docker:     DockerVersion,
+		Calico:     CalicoVersion,
+		Flannel:    FlannelVersion,
+		Defaults:   defaultVersions(),
+	})
+	if err != nil {
+		os.Exit(err.Error(), 1)
+	}
+	for _, warning := range warnings {
+		util.PrintWarning(warning)
 	}
 	kubeSemVer, _ := version.NewVersion(KubeVersion)
-	minKubeVer, _ := version.NewVersion("1.17.0")
-	if kubeSemVer.LessThan(minKubeVer) {
-		os.Exit(fmt.Sprintf("Minimum supported kubernetes version is \"%s\"", minKubeVer), 1)
+	dualStackMinKubeVer, _ := version.NewVersion("1.21.0")
+	if cfg.DeploymentCfg.DualStack() && kubeSemVer.LessThan(dualStackMinKubeVer) {
+		os.Exit(fmt.Sprintf("Dual-stack requires kubernetes \"%s\" or newer", dualStackMinKubeVer), 1)
+	}
+	if IsoPath != "" && cfg.DeploymentCfg.Kubernetes.CNI.GetPlugin() == model.CNIPluginCalico &&
+		cfg.DeploymentCfg.Kubernetes.Calico.GetMode() == model.CalicoModeOperator {
+		os.Exit("Calico operator mode is not supported in offline installation", 1)
+	}
+	nftablesMinKubeVer, _ := version.NewVersion("1.31.0")
+	if cfg.DeploymentCfg.Kubernetes.KubeProxy.GetMode() == model.KubeProxyModeNFTables &&
+		kubeSemVer.LessThan(nftablesMinKubeVer) {
+		os.Exit(fmt.Sprintf("kube-proxy nftables mode requires kubernetes \"%s\" or newer", nftablesMinKubeVer), 1)
 	}
 	if kubeSemVer.String() != KubeVersion {
-		os.Exit(fmt.Sprintf("Kubernetes version needed to be defined exactly. ex: %s", kubeSemVer.String()), 1)
+		os.Exit(fmt.Sprintf("Kubernetes version needed to be exact, a minor version or \"%s\". ex: %s",
+			LatestKubeVersion, kubeSemVer.String()), 1)
+	}
+	err = cfg.DeploymentCfg.ValidateKubeadmVersion(KubeVersion)
+	if err != nil {
+		os.Exit(err.Error(), 1)
+	}
+	err = cfg.DeploymentCfg.ValidateKubeletVersion(KubeVersion)
+	if err != nil {
+		os.Exit(err.Error(), 1)
 	}
-	toggleDebug()
-	os.DetectOS()
-	path.CalculatePaths()
-	cfg.ReadConfig()
 	os.RunCommand(fmt.Sprintf("mkdir -p %s", path.GetTKubeResourcesDir()), true)
 	for _, kubeNode := range cfg.DeploymentCfg.GetKubeNodes() {
 		err := conn.CheckSSHConnection(&conn.Node{IP: kubeNode.IP, Hostname: kubeNode.Hostname, SSHPort: 22})
 		if err != nil {
 			os.Exit(err.Error(), 1)
 		}
-		os.AddToSudoers(kubeNode.IP)
+		os.PreparePrivilegeEscalation(kubeNode.IP)
 		// todo check sshpass
 		os.RunCommandOn(fmt.Sprintf("mkdir -p %s", path.GetTKubeTmpDir(kubeNode.IP)),
 			kubeNode.IP, true)
@@ -78,3 +130,46 @@ func PreRun() {
 		}
 	}
 }
+
+// loadCredentials registers the credentials given with --auth-file and the deprecated --auth-map flags. They are
+// only kept in memory and are not written to the credential store.
+func loadCredentials() {
+	if AuthFile != "" {
+		err := conn.LoadAuthFile(AuthFile)
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+	}
+	if AuthMapStr == "" {
+		return
+	}
+	for _, authInfo := range strings.Split(AuthMapStr, ",") {
+		authInfoSlice := strings.Split(authInfo, ":")
+		if len(authInfoSlice) == 3 {
+			conn.AddAuthMapEntry(authInfoSlice[0], authInfoSlice[1], authInfoSlice[2], "")
+		} else if len(authInfoSlice) == 4 {
+			conn.AddAuthMapEntry(authInfoSlice[0], authInfoSlice[1], authInfoSlice[2], authInfoSlice[3])
+		} else {
+			os.Exit(fmt.Sprintf("Auth data invalid: \"%s\", use --auth-file for passwords including ':' or ','",
+				authInfoSlice[0]), 1)
+		}
+	}
+}
+
+// PreRunCluster selects the cluster given with --cluster or the current one and starts writing the log file of it.
+func PreRunCluster() {
+	err := path.ResolveCluster()
+	if err != nil {
+		os.Exit(err.Error(), 1)
+	}
+	toggleDebug()
+	enableFileLog()
+}
+
+// PreRunConfig prepares reading and writing the config files without connecting to kubernetes nodes.
+func PreRunConfig() {
+	PreRunCluster()
+	loadCredentials()
+	os.RemoteNode = &conn.Node{IP: os.RemoteNodeIP}
+	path.CalculatePaths()
+}
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"fmt"
+	"net"
+
+	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/kube"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/util"
+	"github.com/guumaster/logsymbols"
+)
+
+const apiServerManifest = "/etc/kubernetes/manifests/kube-apiserver.yaml"
+
+// readEncryptionConfig returns the encryption config on the master, found is false if it does not have one.
+func readEncryptionConfig(ip net.IP) (ec kube.EncryptionCfg, found bool) {
+	// the encryption directory is readable only by root
+	data, found, err := os.ReadRootFile(model.EncryptionConfigFile, ip)
+	os.ThrowIfError(err, 1)
+	if !found {
+		return ec, false
+	}
+	ec, err = kube.ParseEncryptionCfg(data)
+	os.ThrowIfError(err, 1)
+	return ec, true
+}
+
+// writeEncryptionConfig writes the encryption config to the master, only root can read the keys.
+func writeEncryptionConfig(ec kube.EncryptionCfg, masterNode model.KubeNode) {
+	data, err := ec.Marshal()
+	os.ThrowIfError(err, 1)
+	os.RunCommandOn(fmt.Sprintf("sudo mkdir -p %s && sudo chown root:root %s && sudo chmod 700 %s",
+		model.EncryptionDir, model.EncryptionDir, model.EncryptionDir), masterNode.IP, true)
+	os.CreateSecretFile(data, model.EncryptionConfigFile, masterNode.IP)
+}
+
+// distributeEncryptionConfig copies the encryption config of the master at srcIP to the master. All masters must
+// share the same keys, a new config is generated only when the source does not have one yet.
+func distributeEncryptionConfig(masterNode model.KubeNode, srcIP net.IP) {
+	encryption := cfg.DeploymentCfg.Kubernetes.EncryptionAtRest
+	if !encryption.Enabled {
+		return
+	}
+	ec, found := readEncryptionConfig(srcIP)
+	if !found {
+		if !srcIP.Equal(masterNode.IP) {
+			os.Exit(fmt.Sprintf("Encryption config not found on \"%s\"", srcIP), 1)
+		}
+		var err error
+		ec, err = kube.NewEncryptionCfg(encryption.GetProvider())
+		os.ThrowIfError(err, 1)
+	}
+	writeEncryptionConfig(ec, masterNode)
+}
+
+// RotateEncryptionKey replaces the key encrypting secrets. The new key is rolled out before it is used, so every
+// apiserver can read secrets written by the others during the rotation. Old keys are dropped after all secrets are
+// rewritten with the new one.
+func RotateEncryptionKey() {
+	encryption := cfg.DeploymentCfg.Kubernetes.EncryptionAtRest
+	if !encryption.Enabled {
+		os.Exit("Encryption at rest is not enabled in deployment config", 1)
+	}
+	masters := cfg.DeploymentCfg.GetMasterKubeNodes()
+	ec, found := readEncryptionConfig(masters[0].IP)
+	if !found {
+		os.Exit(fmt.Sprintf("Encryption config not found on \"%s\"", masters[0].Hostname), 1)
+	}
+	err := ec.AddKey(encryption.GetProvider())
+	os.ThrowIfError(err, 1)
+	rollOutEncryptionConfig(ec, masters, "Adding new encryption key")
+	ec.PromoteNewestKey()
+	rollOutEncryptionConfig(ec, masters, "Encrypting with new key")
+	util.StartSpinner("Rewriting secrets with new key")
+	os.RunCommandOn("kubectl get secrets --all-namespaces -o json | kubectl replace -f -", masters[0].IP, true)
+	util.StopSpinner("", logsymbols.Success)
+	ec.DropOldKeys()
+	rollOutEncryptionConfig(ec, masters, "Removing old encryption keys")
+}
+
+func rollOutEncryptionConfig(ec kube.EncryptionCfg, masters []model.KubeNode, msg string) {
+	for _, masterNode := range masters {
+		util.StartSpinner(fmt.Sprintf("%s on \"%s\"", msg, masterNode.Hostname))
+		writeEncryptionConfig(ec, masterNode)
+		restartApiServer(masterNode)
+		util.StopSpinner("", logsymbols.Success)
+	}
+}
+
+// restartApiServer restarts the apiserver static pod, it reads the encryption config and audit policy only on start.
+func restartApiServer(masterNode model.KubeNode) {
+	startedAt := kube.ApiServerStartedAt(masterNode.Hostname)
+	os.RunCommandOn(fmt.Sprintf("sudo mv %s /etc/kubernetes/kube-apiserver.yaml", apiServerManifest),
+		masterNode.IP, true)
+	util.UpdateSpinner(fmt.Sprintf("Waiting apiserver to stop on \"%s\"", masterNode.Hostname))
+	kube.WaitUntilApiServerStopped(masterNode.Hostname)
+	os.RunCommandOn(fmt.Sprintf("sudo mv /etc/kubernetes/kube-apiserver.yaml %s", apiServerManifest),
+		masterNode.IP, true)
+	util.UpdateSpinner(fmt.Sprintf("Waiting apiserver on \"%s\"", masterNode.Hostname))
+	kube.WaitUntilApiServerRestarted(masterNode.Hostname, startedAt)
+}
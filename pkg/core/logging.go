@@ -1,10 +1,16 @@
 package core
 
 import (
-	"github.com/fatih/color"
-	log "github.com/sirupsen/logrus"
+	"fmt"
 	"io"
 	logA "log"
+	goos "os"
+	"strings"
+	"time"
+
+	"com.github.tunahansezen/tkube/pkg/path"
+	"github.com/fatih/color"
+	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -24,6 +30,33 @@ func (f *PlainFormatter) Format(entry *log.Entry) ([]byte, error) {
 	return []byte(color.CyanString(entry.Message + "\n")), nil
 }
 
+// fileHook writes log entries without colors to the log file of the cluster. The file is created with the first
+// entry, so commands which do not log anything leave no empty files behind.
+type fileHook struct {
+	path string
+	file *goos.File
+}
+
+func (h *fileHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (h *fileHook) Fire(entry *log.Entry) error {
+	if h.file == nil {
+		err := goos.MkdirAll(path.GetTKubeLogsDir(), goos.FileMode(0700))
+		if err != nil {
+			return err
+		}
+		h.file, err = goos.OpenFile(h.path, goos.O_CREATE|goos.O_WRONLY|goos.O_APPEND, goos.FileMode(0600))
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(h.file, "%s %-5s %s\n", entry.Time.Format(time.RFC3339),
+		strings.ToUpper(entry.Level.String()), entry.Message)
+	return err
+}
+
 func toggleDebug() {
 	logA.SetOutput(io.Discard)
 	if Trace {
@@ -33,3 +66,9 @@ func toggleDebug() {
 	}
 	log.SetFormatter(new(PlainFormatter))
 }
+
+func enableFileLog() {
+	log.AddHook(&fileHook{
+		path: fmt.Sprintf("%s/tkube-%s.log", path.GetTKubeLogsDir(), time.Now().Format("20060102-150405")),
+	})
+}
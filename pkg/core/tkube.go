@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/config/compat"
 	"com.github.tunahansezen/tkube/pkg/config/model"
 	"com.github.tunahansezen/tkube/pkg/config/templates"
 	conn "com.github.tunahansezen/tkube/pkg/connection"
@@ -33,6 +35,7 @@ const (
 	DefaultEtcdVersion       = "3.5.25"
 	DefaultKubeVersion       = "1.34.2"
 	DefaultCalicoVersion     = "auto"
+	DefaultFlannelVersion    = "auto"
 	DefaultHelmVersion       = "3.13.3"
 	DefaultHelmfileVersion   = "0.160.0"
 	DefaultDockerPrune       = false
@@ -42,14 +45,17 @@ const (
 var (
 	//go:embed resources
 	f                      embed.FS
-	AuthMapStr             string // node1IP:node1SshUser:node1SshPass,node2IP:node2SshUser:node2SshPass...
+	AuthMapStr             string // deprecated, node1IP:node1SshUser:node1SshPass[:node1SshPrivateKeyPath],...
+	AuthFile               string
 	DockerVersion          string
 	ContainerdVersion      string
 	EtcdVersion            string
 	KubeVersion            string
 	CalicoVersion          string
+	FlannelVersion         string
 	HelmVersion            string
 	HelmfileVersion        string
+	KubeVipIsoVersion      string
 	IsoPath                string
 	etcdCompressedFile     string
 	etcdUrl                string
@@ -78,7 +84,6 @@ func Install(nodes model.KubeNodes, masterRecovery bool) {
 			}
 		}
 	}
-	handleAuthMap()
 	addToEtcHosts(cfg.DeploymentCfg.GetKubeNodes())
 	if IsoPath != "" {
 		var firstMasterNode model.KubeNode
@@ -156,50 +161,30 @@ func Install(nodes model.KubeNodes, masterRecovery bool) {
 	if IsoPath != "" { // todo make for online
 		installHelmPlugins(nodes)
 	}
-	if cfg.DeploymentCfg.Keepalived.Enabled {
+	if cfg.DeploymentCfg.KeepalivedEnabled() {
 		installKeepAliveD(nodes)
 	}
+	if cfg.DeploymentCfg.LoadBalancer.Enabled() {
+		installHAProxy(nodes)
+	}
 	if IsoPath != "" && !SkipImageLoad {
 		kubeSemVer, _ := version.NewVersion(KubeVersion)
 		kube124Ver, _ := version.NewVersion("1.24")
 		for _, node := range nodes.Nodes {
 			util.StartSpinner(fmt.Sprintf("Loading images on \"%s\"", node.Hostname))
-			if kubeSemVer.GreaterThanOrEqual(kube124Ver) {
-				os.RunCommandOn(fmt.Sprintf("ls -1 %s/kubernetes/images/*.tar | "+
-					"xargs --no-run-if-empty -L 1 sudo ctr -n=k8s.io images import", constant.IsoMountDir),
-					node.IP, true)
-				os.RunCommandOn(fmt.Sprintf("ls -1 %s/calico/images/*.tar | "+
-					"xargs --no-run-if-empty -L 1 sudo ctr -n=k8s.io images import", constant.IsoMountDir),
-					node.IP, true)
-			} else {
-				os.RunCommandOn(fmt.Sprintf("ls -1 %s/kubernetes/images/*.tar | "+
-					"xargs --no-run-if-empty -L 1 sudo docker load -i", constant.IsoMountDir),
-					node.IP, true)
-				os.RunCommandOn(fmt.Sprintf("ls -1 %s/calico/images/*.tar | "+
-					"xargs --no-run-if-empty -L 1 sudo docker load -i", constant.IsoMountDir),
-					node.IP, true)
+			for _, imageDir := range []string{"kubernetes", "calico", "flannel", "kube-vip"} {
+				importCmd := "docker load -i"
+				if kubeSemVer.GreaterThanOrEqual(kube124Ver) {
+					importCmd = "ctr -n=k8s.io images import"
+				}
+				os.RunIdempotentCommandOn(fmt.Sprintf("ls -1 %s/%s/images/*.tar 2>/dev/null | "+
+					"sudo xargs --no-run-if-empty -L 1 %s", constant.IsoMountDir, imageDir, importCmd), node.IP, true)
 			}
 			util.StopSpinner("", logsymbols.Success)
 		}
 	}
 	initKubernetes(nodes, masterRecovery)
-}
-
-func handleAuthMap() {
-	if AuthMapStr == "" {
-		return
-	}
-	for _, authInfo := range strings.Split(AuthMapStr, ",") {
-		authInfoSlice := strings.Split(authInfo, ":")
-		if len(authInfoSlice) != 3 {
-			os.Exit("Auth data invalid", 1)
-		} else {
-			err := conn.WriteSSHData(authInfoSlice[0], authInfoSlice[1], authInfoSlice[2], authInfoSlice[3])
-			if err != nil {
-				os.Exit(err.Error(), 1)
-			}
-		}
-	}
+	recordVersions(nodes.Nodes[0].IP)
 }
 
 func addToEtcHosts(nodes []model.KubeNode) {
@@ -219,6 +204,7 @@ func prepareNodes(nodes model.KubeNodes) {
 		//os.RunCommandOn("sudo mount -t cgroup -o cpu,cpuacct none /sys/fs/cgroup/cpu,cpuacct || true", kubeNode.IP, true)
 		//os.RunCommandOn("sudo mkdir -p /sys/fs/cgroup/systemd", kubeNode.IP, true)
 		//os.RunCommandOn("sudo mount -t cgroup -o none,name=systemd cgroup /sys/fs/cgroup/systemd || true", kubeNode.IP, true)
+		loadKernelModules(kubeNode, cfg.DeploymentCfg.Kubernetes.KubeProxy.KernelModules())
 		if (os.OS == os.CentOS || os.OS == os.Rocky || os.OS == os.Redhat) && cfg.DeploymentCfg.CentOS.SetSelinuxPermissive {
 			if os.IsSelinuxEnabled(kubeNode.IP) {
 				util.StartSpinner("Setting SELinux to permissive mode")
@@ -232,6 +218,17 @@ func prepareNodes(nodes model.KubeNodes) {
 	}
 }
 
+// loadKernelModules loads modules now and on every boot.
+func loadKernelModules(kubeNode model.KubeNode, modules []string) {
+	if len(modules) == 0 {
+		return
+	}
+	os.CreateFile([]byte(strings.Join(modules, "\n")+"\n"), "/etc/modules-load.d/kube-proxy.conf", kubeNode.IP)
+	for _, module := range modules {
+		os.RunCommandOn(fmt.Sprintf("sudo modprobe %s", module), kubeNode.IP, true)
+	}
+}
+
 func addCustomRepos(nodes model.KubeNodes) {
 	if IsoPath != "" {
 		log.Debugf("Skipping adding custom repo, because iso repo defined.")
@@ -266,7 +263,8 @@ func addCustomRepos(nodes model.KubeNodes) {
 }
 
 func installPackages(nodes model.KubeNodes) {
-	for _, packageName := range cfg.DeploymentCfg.Packages {
+	packages := slices.Concat(cfg.DeploymentCfg.Packages, cfg.DeploymentCfg.Kubernetes.KubeProxy.Packages())
+	for _, packageName := range packages {
 		for _, node := range nodes.Nodes {
 			os.InstallPackage(packageName, node.IP)
 		}
@@ -404,6 +402,25 @@ func createDockerDaemonCfgOn(ip net.IP) {
 		path.GetTKubeTmpDir(ip)), ip, true)
 }
 
+// addKubeRepo adds the kubernetes repo to the node, "{version}" in the repo address is replaced with the minor
+// version of kubeVersion.
+func addKubeRepo(kubeVersion string, ip net.IP) {
+	repo := cfg.DeploymentCfg.Kubernetes.Repo
+	minorVersion := util.GetMajorVersion(kubeVersion)
+	util.StartSpinner("Adding kubernetes repo")
+	var repoName string
+	if strings.Contains(repo.Address, "{version}") {
+		repoName = fmt.Sprintf("%s-%s", repo.ShortName(), minorVersion)
+	} else {
+		repoName = repo.ShortName()
+	}
+	keyPath := os.AddGpgKey(strings.ReplaceAll(repo.Key, "{version}", minorVersion), repoName, ip)
+	os.AddRepository(repo.Name, repo.ShortName(), repoName,
+		strings.ReplaceAll(repo.Address, "{version}", minorVersion), keyPath, ip)
+	util.StopSpinner("", logsymbols.Success)
+	os.UpdateRepos(ip)
+}
+
 func removeKubePackagesIfNecessary(nodes model.KubeNodes) map[string]bool {
 	repo := cfg.DeploymentCfg.Kubernetes.Repo
 	installationRequired := make(map[string]bool)
@@ -413,19 +430,7 @@ func removeKubePackagesIfNecessary(nodes model.KubeNodes) map[string]bool {
 			log.Debugf("Skipping to add kube repo on %s. Because iso repo defined.", kubeNode.IP.String())
 		}
 		if repo.Enabled && !isoPathDefined {
-			util.StartSpinner("Adding kubernetes repo")
-			var repoName string
-			if strings.Contains(repo.Address, "{version}") {
-				repoName = fmt.Sprintf("%s-%s", repo.ShortName(), util.GetMajorVersion(KubeVersion))
-			} else {
-				repoName = repo.ShortName()
-			}
-			keyPath := os.AddGpgKey(strings.ReplaceAll(repo.Key, "{version}", util.GetMajorVersion(KubeVersion)),
-				repoName, kubeNode.IP)
-			os.AddRepository(repo.Name, repo.ShortName(), repoName,
-				strings.ReplaceAll(repo.Address, "{version}", util.GetMajorVersion(KubeVersion)), keyPath, kubeNode.IP)
-			util.StopSpinner("", logsymbols.Success)
-			os.UpdateRepos(kubeNode.IP)
+			addKubeRepo(KubeVersion, kubeNode.IP)
 		}
 		isKubeletInstalled, installedKubeletVer := os.PackageInstalledOn("kubelet", kubeNode.IP)
 		isKubectlInstalled, installedKubectlVer := os.PackageInstalledOn("kubectl", kubeNode.IP)
@@ -438,7 +443,10 @@ func removeKubePackagesIfNecessary(nodes model.KubeNodes) map[string]bool {
 		os.RunCommandOn("sudo rm -rf /etc/kubernetes", kubeNode.IP, true)
 		os.RunCommandOn("sudo rm -rf /etc/cni/net.d", kubeNode.IP, true)
 		os.RunCommandOn("sudo rm -rf /var/lib/cni", kubeNode.IP, true)
-		// todo check ipvsadm exist, run "ipvsadm --clear"
+		os.RunCommandOn("sudo rm -rf /run/flannel", kubeNode.IP, true)
+		os.RunCommandOn("sudo ip link delete flannel.1 2>/dev/null || true", kubeNode.IP, true)
+		os.RunCommandOn("sudo ip link delete cni0 2>/dev/null || true", kubeNode.IP, true)
+		os.RunCommandOn("! command -v ipvsadm >/dev/null || sudo ipvsadm --clear", kubeNode.IP, true)
 		os.RunCommandOn("sudo rm -rf $HOME/.kube", kubeNode.IP, true)
 		if isKubeletInstalled {
 			os.RunCommandOn("sudo service kubelet stop || true", kubeNode.IP, true)
@@ -670,13 +678,14 @@ func installEtcd(nodes model.KubeNodes) {
 	}
 	var clusterAddresses []string
 	for _, k := range cfg.DeploymentCfg.GetMasterKubeNodes() {
-		clusterAddresses = append(clusterAddresses, fmt.Sprintf("%s=https://%s:2380", k.Hostname, k.IP))
+		clusterAddresses = append(clusterAddresses, fmt.Sprintf("%s=https://%s:2380", k.Hostname,
+			util.URLHost(k.IP)))
 	}
 	for _, kubeNode := range nodes.GetMasterKubeNodes() {
 		util.StartSpinner(fmt.Sprintf("Starting etcd service on \"%s\"", kubeNode.Hostname))
 		os.RunCommandOn("sudo mkdir -p /var/lib/etcd", kubeNode.IP, true)
 		etcdSvcCfg := string(etcdSvcCfgBytes)
-		etcdSvcCfg = strings.ReplaceAll(etcdSvcCfg, "${HOST_IP}", kubeNode.IP.String())
+		etcdSvcCfg = strings.ReplaceAll(etcdSvcCfg, "${HOST_IP}", util.URLHost(kubeNode.IP))
 		etcdSvcCfg = strings.ReplaceAll(etcdSvcCfg, "${HOSTNAME}", kubeNode.Hostname)
 		etcdSvcCfg = strings.ReplaceAll(etcdSvcCfg, "\\\n", "")
 		etcdSvcCfg = strings.ReplaceAll(etcdSvcCfg, "${CLUSTER_ADDRESSES}", strings.Join(clusterAddresses, ","))
@@ -694,7 +703,7 @@ func installEtcd(nodes model.KubeNodes) {
 	time.Sleep(5 * time.Second)
 	var endpoints []string
 	for _, k := range cfg.DeploymentCfg.GetMasterKubeNodes() {
-		endpoints = append(endpoints, fmt.Sprintf("https://%s:2379", k.IP))
+		endpoints = append(endpoints, fmt.Sprintf("https://%s:2379", util.URLHost(k.IP)))
 	}
 	allEtcdStarted := false
 	retry := 5
@@ -909,36 +918,157 @@ func installKeepAliveD(nodes model.KubeNodes) {
 		os.RunCommandOn("sudo mkdir -p /etc/keepalived", masterNode.IP, true)
 		os.RunCommandOn("sudo chmod -R 777 /etc/keepalived", masterNode.IP, true)
 		os.InstallPackage("keepalived", masterNode.IP)
-		keepalivedConfVars := util.TemplateVars{
-			"Interface":       masterNode.Interface,
-			"VirtualRouterID": cfg.DeploymentCfg.Keepalived.VirtualRouterId,
-			"VirtualIP":       cfg.DeploymentCfg.Keepalived.VirtualIP,
-			"Priority":        100,     // todo add to cfg
-			"AuthPass":        "tkube", // todo add to cfg
+		configureKeepAliveD(masterNode)
+		os.RunCommandOn("sudo service keepalived restart", masterNode.IP, true)
+	}
+	if !cfg.DeploymentCfg.Keepalived.Unicast {
+		return
+	}
+	// unicast peers of the other masters include the new ones
+	for _, masterNode := range cfg.DeploymentCfg.GetMasterKubeNodes() {
+		if !nodes.Contains(masterNode.IP) {
+			configureKeepAliveD(masterNode)
+			os.RunCommandOn("sudo systemctl reload keepalived || sudo systemctl restart keepalived",
+				masterNode.IP, true)
 		}
-		rendered, err := util.RenderTemplate(templates.KeepalivedConf, keepalivedConfVars)
-		os.ThrowIfError(err, 1)
-		os.CreateFile([]byte(rendered), fmt.Sprintf("/etc/keepalived/%s", templates.KeepalivedConf.Name()),
-			masterNode.IP)
-		checkApiserverShVars := util.TemplateVars{
-			"VirtualIP": cfg.DeploymentCfg.Keepalived.VirtualIP,
+	}
+}
+
+// ApplyKeepAliveD renders keepalived config on masters again and reloads keepalived without reinstalling it.
+func ApplyKeepAliveD() {
+	if !cfg.DeploymentCfg.KeepalivedEnabled() {
+		os.Exit("keepalived is not enabled in deployment config", 1)
+	}
+	for _, masterNode := range cfg.DeploymentCfg.GetMasterKubeNodes() {
+		util.StartSpinner(fmt.Sprintf("Applying keepalived config on \"%s\"", masterNode.Hostname))
+		configureKeepAliveD(masterNode)
+		os.RunCommandOn("sudo systemctl reload keepalived || sudo systemctl restart keepalived", masterNode.IP,
+			true)
+		util.StopSpinner("", logsymbols.Success)
+	}
+}
+
+func configureKeepAliveD(masterNode model.KubeNode) {
+	keepalived := cfg.DeploymentCfg.Keepalived
+	keepalivedNode := cfg.DeploymentCfg.GetKeepalivedNode(masterNode)
+	keepalivedConfVars := util.TemplateVars{
+		"Interface":       masterNode.Interface,
+		"VirtualRouterID": keepalived.VirtualRouterId,
+		"VirtualIP":       keepalived.VirtualIP,
+		"State":           keepalivedNode.State,
+		"Priority":        keepalivedNode.Priority,
+		"AuthPass":        keepalived.AuthPass,
+		"IPv6":            keepalived.VirtualIP.To4() == nil,
+		"NoPreempt":       keepalived.NoPreempt,
+		"Unicast":         keepalived.Unicast,
+		"UnicastSrcIP":    masterNode.AddressOfFamily(keepalived.VirtualIP),
+		"UnicastPeers":    cfg.DeploymentCfg.GetKeepalivedPeers(masterNode),
+		"Check":           keepalived.Check.WithDefaults(),
+		"Notify":          keepalived.Notify,
+	}
+	rendered, err := util.RenderTemplate(templates.KeepalivedConf, keepalivedConfVars)
+	os.ThrowIfError(err, 1)
+	os.CreateFile([]byte(rendered), fmt.Sprintf("/etc/keepalived/%s", templates.KeepalivedConf.Name()),
+		masterNode.IP)
+	checkApiserverShVars := util.TemplateVars{
+		"VirtualIP":     keepalived.VirtualIP,
+		"VirtualIPHost": util.URLHost(keepalived.VirtualIP),
+		"Port":          cfg.DeploymentCfg.GetControlPlanePort(),
+	}
+	rendered, err = util.RenderTemplate(templates.CheckApiserverSh, checkApiserverShVars)
+	os.ThrowIfError(err, 1)
+	os.CreateFile([]byte(rendered), fmt.Sprintf("/etc/keepalived/%s", templates.CheckApiserverSh.Name()),
+		masterNode.IP)
+	os.RunCommandOn(fmt.Sprintf("sudo chmod -R 644 %s", "/etc/keepalived"), masterNode.IP, true)
+	os.RunCommandOn(fmt.Sprintf("sudo chmod +x /etc/keepalived/%s", templates.CheckApiserverSh.Name()),
+		masterNode.IP, true)
+}
+
+// writeKubeVipManifest creates the kube-vip static pod. The first master gets it before kubeadm init, other masters
+// after they have joined and have admin.conf. admin.conf has no permissions before kubeadm init completes since 1.29,
+// so the first master starts with super-admin.conf.
+func writeKubeVipManifest(masterNode model.KubeNode, bootstrap bool) {
+	kubeVip := cfg.DeploymentCfg.ControlPlaneVIP.KubeVip
+	if KubeVipIsoVersion != "" {
+		kubeVip.Version = KubeVipIsoVersion
+	}
+	kubeConfig := "/etc/kubernetes/admin.conf"
+	kubeSemVer, _ := version.NewVersion(KubeVersion)
+	kube129Ver, _ := version.NewVersion("1.29")
+	if bootstrap && kubeSemVer.GreaterThanOrEqual(kube129Ver) {
+		kubeConfig = "/etc/kubernetes/super-admin.conf"
+	}
+	var peers []string
+	for _, peer := range kubeVip.BGP.Peers {
+		peers = append(peers, fmt.Sprintf("%s:%d:%s:false", peer, kubeVip.BGP.PeerAS, kubeVip.BGP.Password))
+	}
+	cidr := 32
+	if cfg.DeploymentCfg.GetVirtualIP().To4() == nil {
+		cidr = 128
+	}
+	kubeVipVars := util.TemplateVars{
+		"Image":      kubeVip.GetImage(),
+		"VirtualIP":  cfg.DeploymentCfg.GetVirtualIP(),
+		"Port":       constant.ApiServerPort,
+		"CIDR":       cidr,
+		"Interface":  masterNode.Interface,
+		"Mode":       kubeVip.GetMode(),
+		"RouterID":   masterNode.IP,
+		"BGP":        kubeVip.BGP,
+		"BGPPeers":   strings.Join(peers, ","),
+		"KubeConfig": kubeConfig,
+	}
+	rendered, err := util.RenderTemplate(templates.KubeVipYaml, kubeVipVars)
+	os.ThrowIfError(err, 1)
+	os.RunCommandOn("sudo mkdir -p /etc/kubernetes/manifests", masterNode.IP, true)
+	os.CreateFile([]byte(rendered), fmt.Sprintf("/etc/kubernetes/manifests/%s", templates.KubeVipYaml.Name()),
+		masterNode.IP)
+}
+
+func installHAProxy(nodes model.KubeNodes) {
+	for _, masterNode := range nodes.GetMasterKubeNodes() {
+		util.StartSpinner(fmt.Sprintf("Installing haproxy on \"%s\"", masterNode.Hostname))
+		os.InstallPackage("haproxy", masterNode.IP)
+		configureHAProxy(masterNode)
+		os.RunCommandOn("sudo systemctl enable haproxy && sudo systemctl restart haproxy", masterNode.IP, true)
+		util.StopSpinner("", logsymbols.Success)
+	}
+	// backends of the other masters include the new ones
+	for _, masterNode := range cfg.DeploymentCfg.GetMasterKubeNodes() {
+		if !nodes.Contains(masterNode.IP) {
+			configureHAProxy(masterNode)
+			os.RunCommandOn("sudo systemctl reload haproxy", masterNode.IP, true)
 		}
-		rendered, err = util.RenderTemplate(templates.CheckApiserverSh, checkApiserverShVars)
-		os.ThrowIfError(err, 1)
-		os.CreateFile([]byte(rendered), fmt.Sprintf("/etc/keepalived/%s", templates.CheckApiserverSh.Name()),
-			masterNode.IP)
-		os.RunCommandOn(fmt.Sprintf("sudo chmod -R 644 %s", "/etc/keepalived"), masterNode.IP, true)
-		os.RunCommandOn(fmt.Sprintf("sudo chmod +x /etc/keepalived/%s", templates.CheckApiserverSh.Name()),
-			masterNode.IP, true)
-		os.RunCommandOn("sudo service keepalived restart", masterNode.IP, true)
 	}
 }
 
+func configureHAProxy(masterNode model.KubeNode) {
+	type server struct {
+		Name    string
+		Address string
+	}
+	var servers []server
+	for _, master := range cfg.DeploymentCfg.GetMasterKubeNodes() {
+		servers = append(servers, server{Name: master.Hostname,
+			Address: net.JoinHostPort(master.IP.String(), strconv.Itoa(constant.ApiServerPort))})
+	}
+	haproxyCfgVars := util.TemplateVars{
+		"Port":    cfg.DeploymentCfg.LoadBalancer.GetPort(),
+		"IPv6":    cfg.DeploymentCfg.GetVirtualIP().To4() == nil,
+		"Servers": servers,
+	}
+	rendered, err := util.RenderTemplate(templates.HAProxyCfg, haproxyCfgVars)
+	os.ThrowIfError(err, 1)
+	cfgFile := fmt.Sprintf("/etc/haproxy/%s", templates.HAProxyCfg.Name())
+	os.CreateFile([]byte(rendered), cfgFile, masterNode.IP)
+	os.RunCommandOn(fmt.Sprintf("sudo haproxy -c -f %s", cfgFile), masterNode.IP, true)
+}
+
 func initKubernetes(nodes model.KubeNodes, masterRecovery bool) {
 	var firstMasterNode *model.KubeNode
 	var certKey string
+	var mainMasterIP net.IP
 	if masterRecovery {
-		var mainMasterIP net.IP
 		if nodes.Nodes[0].Hostname == cfg.DeploymentCfg.Nodes[0].Hostname {
 			mainMasterIP = cfg.DeploymentCfg.Nodes[1].IP
 		} else {
@@ -950,53 +1080,41 @@ func initKubernetes(nodes model.KubeNodes, masterRecovery bool) {
 		firstMasterNode = &nodes.GetMasterKubeNodes()[0]
 		util.StartSpinner(fmt.Sprintf("Initializing kubernetes on \"%s\"", firstMasterNode.Hostname))
 		certKey = kube.CreateCertKey(KubeVersion, firstMasterNode.IP)
-		controlPlaneIP := firstMasterNode.IP
-		if cfg.DeploymentCfg.Keepalived.Enabled {
-			controlPlaneIP = cfg.DeploymentCfg.Keepalived.VirtualIP
+		if cfg.DeploymentCfg.KubeVipEnabled() {
+			writeKubeVipManifest(*firstMasterNode, true)
 		}
-		os.CreateFile(kube.CreateCombinedKubeadmCfg(KubeVersion, controlPlaneIP, firstMasterNode.IP, certKey,
+		os.CreateFile(kube.CreateCombinedKubeadmCfg(KubeVersion,
+			cfg.DeploymentCfg.GetControlPlaneEndpoint(firstMasterNode.IP), *firstMasterNode, certKey,
 			cfg.DeploymentCfg, multiMasterDeployment),
-			fmt.Sprintf("%s/kubeadm-config.yaml", path.GetTKubeCfgDir()), firstMasterNode.IP)
+			path.GetKubeadmCfgFile(), firstMasterNode.IP)
 		util.StopSpinner("", logsymbols.Success)
-		kubeConf := "net.bridge.bridge-nf-call-ip6tables = 1\nnet.bridge.bridge-nf-call-iptables = 1\nnet.ipv4.ip_forward = 1\n"
-		os.CreateFile([]byte(kubeConf), "/etc/sysctl.d/kubernetes.conf", firstMasterNode.IP)
+		os.CreateFile([]byte(kubeSysctlConf()), "/etc/sysctl.d/kubernetes.conf", firstMasterNode.IP)
 		os.RunCommandOn("sudo sysctl --system", firstMasterNode.IP, true)
-		os.RunCommandOn(fmt.Sprintf("sudo kubeadm init --config %s/kubeadm-config.yaml --upload-certs",
-			path.GetTKubeCfgDir()), firstMasterNode.IP, false)
+		writeAuditFiles(*firstMasterNode)
+		distributeEncryptionConfig(*firstMasterNode, firstMasterNode.IP)
+		patches := kube.NodePatches(cfg.DeploymentCfg, *firstMasterNode)
+		kube.WritePatches(firstMasterNode.IP, patches)
+		os.RunCommandOn(fmt.Sprintf("sudo kubeadm init --config %s --upload-certs%s%s", path.GetKubeadmCfgFile(),
+			kube.PatchesFlag(KubeVersion, patches), kube.KubeProxySkipFlag(cfg.DeploymentCfg)), firstMasterNode.IP,
+			false)
+		if cfg.DeploymentCfg.KubeVipEnabled() {
+			writeKubeVipManifest(*firstMasterNode, false)
+		}
 		os.RunCommandOn("mkdir -p $HOME/.kube", firstMasterNode.IP, true)
 		os.RunCommandOn("sudo cp /etc/kubernetes/admin.conf $HOME/.kube/config", firstMasterNode.IP, true)
 		os.RunCommandOn("sudo chown $(id -u):$(id -g) $HOME/.kube/config", firstMasterNode.IP, true)
 		os.RunCommandOn("sudo mkdir -p /root/.kube", firstMasterNode.IP, true)
 		os.RunCommandOn("sudo cp /etc/kubernetes/admin.conf /root/.kube/config", firstMasterNode.IP, true)
 		os.RunCommandOn("sudo chown $(id -u):$(id -g) /root/.kube/config", firstMasterNode.IP, true)
-		util.StartSpinner(fmt.Sprintf("Applying calico config \"%s\" with version", getCalicoVersion()))
-		os.RunCommandOn(fmt.Sprintf("mkdir -p %s", path.GetTKubeTmpDir(firstMasterNode.IP)), firstMasterNode.IP, true)
-		var calicoUrl string
-		if IsoPath != "" {
-			calicoUrl = fmt.Sprintf("%s/calico/calico-%s.yaml", constant.IsoMountDir, CalicoVersion)
-		}
-		if strings.HasPrefix(calicoUrl, "/") { // check local file
-			os.RunCommandOn(fmt.Sprintf("mkdir -p %s && sudo cp %s %s/calico.yaml",
-				path.GetTKubeTmpDir(firstMasterNode.IP), calicoUrl,
-				path.GetTKubeTmpDir(firstMasterNode.IP)), firstMasterNode.IP, true)
-		} else {
-			os.RunCommandOn(fmt.Sprintf("rm -f %s/calico.yaml && wget -nc -qO %s/calico.yaml %s --no-check-certificate",
-				path.GetTKubeTmpDir(firstMasterNode.IP), path.GetTKubeTmpDir(firstMasterNode.IP),
-				cfg.DeploymentCfg.GetCalicoExactUrl(getCalicoVersion())), firstMasterNode.IP, true)
-		}
-		if cfg.DeploymentCfg.Kubernetes.ImageRegistry != constant.DefaultKubeImageRegistry {
-			os.RunCommandOn(fmt.Sprintf("sed -i 's/docker.io/%s/' %s/calico.yaml",
-				cfg.DeploymentCfg.Kubernetes.ImageRegistry, path.GetTKubeTmpDir(firstMasterNode.IP)),
-				firstMasterNode.IP, true)
-		}
-		os.RunCommandOn(fmt.Sprintf("kubectl apply -f %s/calico.yaml", path.GetTKubeTmpDir(firstMasterNode.IP)),
-			firstMasterNode.IP, true)
-		util.StopSpinner("", logsymbols.Success)
+		applyCNI(*firstMasterNode)
 	}
 
 	if firstMasterNode == nil {
 		firstMasterNode = &cfg.DeploymentCfg.GetMasterKubeNodes()[0]
 	}
+	if mainMasterIP == nil {
+		mainMasterIP = firstMasterNode.IP
+	}
 
 	for _, workerNode := range nodes.GetWorkerKubeNodes() {
 		os.RunCommandOn("mkdir -p $HOME/.kube", workerNode.IP, true)
@@ -1006,69 +1124,108 @@ func initKubernetes(nodes model.KubeNodes, masterRecovery bool) {
 		}
 	}
 
-	for _, env := range cfg.DeploymentCfg.Kubernetes.Calico.EnvVars {
-		kube.SetEnv("daemonset/calico-node", "kube-system", env)
-	}
 	if !masterRecovery {
 		kube.WaitUntilPodsRunningWithName(kubeSystemPodNames(firstMasterNode.Hostname), "kube-system")
 	}
-	joinAsMasterCmd := ""
-	if len(nodes.GetMasterKubeNodes()) > 1 || masterRecovery {
-		if certKey == "" {
-			certKey = os.RunCommandOn(
-				fmt.Sprintf("cat %s/kubeadm-config.yaml | grep certificateKey | awk -F ':' '{print $2}' | xargs",
-					path.GetTKubeCfgDir()), firstMasterNode.IP, true)
+	var joinInfo kube.JoinInfo
+	joinMasters := len(nodes.GetMasterKubeNodes()) > 1 || masterRecovery
+	if joinMasters || len(nodes.GetWorkerKubeNodes()) > 0 {
+		if joinMasters && certKey == "" {
+			// uploaded certificates expire, they are uploaded again with the key in kubeadm config
+			certKey = kube.UploadCerts(KubeVersion, mainMasterIP)
 		}
-		joinAsMasterCmd = os.RunCommandOn(
-			fmt.Sprintf("sudo kubeadm token create --print-join-command --certificate-key %s", certKey),
-			firstMasterNode.IP, true)
+		// the first master may be the one being recovered, a running master creates the token and is joined to
+		joinInfo = kube.CreateJoinInfo(mainMasterIP, cfg.DeploymentCfg.GetControlPlaneEndpoint(mainMasterIP), certKey)
 	}
 	for _, masterNode := range nodes.GetMasterKubeNodes() {
 		if !masterRecovery && masterNode.IP.Equal(firstMasterNode.IP) {
 			continue
 		}
-		kubeConf := "net.bridge.bridge-nf-call-ip6tables = 1\nnet.bridge.bridge-nf-call-iptables = 1\nnet.ipv4.ip_forward = 1\n"
-		os.CreateFile([]byte(kubeConf), "/etc/sysctl.d/kubernetes.conf", masterNode.IP)
+		os.CreateFile([]byte(kubeSysctlConf()), "/etc/sysctl.d/kubernetes.conf", masterNode.IP)
 		os.RunCommandOn("sudo sysctl --system", masterNode.IP, true)
+		writeAuditFiles(masterNode)
+		distributeEncryptionConfig(masterNode, mainMasterIP)
 		util.StartSpinner(fmt.Sprintf("Master node \"%s\" joining to cluster", masterNode.Hostname))
-		joinCmd := fmt.Sprintf("%s --apiserver-advertise-address=%s", joinAsMasterCmd, masterNode.IP)
-		os.RunCommandOn(fmt.Sprintf("sudo %s", joinCmd), masterNode.IP, true)
+		joinNode(masterNode, joinInfo)
+		// kubeadm join requires an empty manifests directory, the VIP is served by the other masters until then
+		if cfg.DeploymentCfg.KubeVipEnabled() {
+			writeKubeVipManifest(masterNode, false)
+		}
 		util.StopSpinner(fmt.Sprintf("Master node \"%s\" has joined to cluster", masterNode.Hostname),
 			logsymbols.Success)
 		kube.WaitUntilPodsRunningWithName(kubeSystemPodNames(masterNode.Hostname), "kube-system")
-		kube.UpdateServerInfoOnKubeAdminConf(masterNode.IP)
-		kube.UpdateServerInfoOnKubeletConf(masterNode.IP)
+		// masters talk to their own apiserver, unless an external endpoint fronts the control plane
+		server := fmt.Sprintf("https://%s", cfg.DeploymentCfg.GetControlPlaneEndpoint(masterNode.IP))
+		if !cfg.DeploymentCfg.ExternalEndpointEnabled() {
+			server = fmt.Sprintf("https://%s", net.JoinHostPort(masterNode.IP.String(),
+				strconv.Itoa(constant.ApiServerPort)))
+		}
+		kube.UpdateServerInfoOnKubeAdminConf(masterNode.IP, server)
+		kube.UpdateServerInfoOnKubeletConf(masterNode.IP, server)
 		os.RunCommandOn("sudo service kubelet restart", masterNode.IP, true)
 		os.RunCommandOn("mkdir -p $HOME/.kube", masterNode.IP, true)
 		os.RunCommandOn("sudo cp /etc/kubernetes/admin.conf $HOME/.kube/config", masterNode.IP, true)
 		os.RunCommandOn("sudo chown $(id -u):$(id -g) $HOME/.kube/config", masterNode.IP, true)
 	}
-	var joinAsWorkerCmd string
 	for _, workerNode := range nodes.GetWorkerKubeNodes() {
-		kubeConf := "net.bridge.bridge-nf-call-ip6tables = 1\nnet.bridge.bridge-nf-call-iptables = 1\nnet.ipv4.ip_forward = 1\n"
-		os.CreateFile([]byte(kubeConf), "/etc/sysctl.d/kubernetes.conf", workerNode.IP)
+		os.CreateFile([]byte(kubeSysctlConf()), "/etc/sysctl.d/kubernetes.conf", workerNode.IP)
 		os.RunCommandOn("sudo sysctl --system", workerNode.IP, true)
-		if joinAsWorkerCmd == "" {
-			joinAsWorkerCmd = os.RunCommandOn("sudo kubeadm token create --print-join-command",
-				firstMasterNode.IP, true)
-		}
 		util.StartSpinner(fmt.Sprintf("Worker node \"%s\" joining to cluster", workerNode.Hostname))
-		os.RunCommandOn(fmt.Sprintf("sudo %s", joinAsWorkerCmd), workerNode.IP, true)
+		joinNode(workerNode, joinInfo)
 		util.StopSpinner(fmt.Sprintf("Worker node \"%s\" has joined to cluster", workerNode.Hostname),
 			logsymbols.Success)
 	}
+	if !nodes.IncludeMaster() {
+		// the network plugin is pinned to the interfaces of nodes, added nodes may use another one. It is applied
+		// from an added node, which has the kubeconfig and the iso in offline installation.
+		applyCNI(nodes.Nodes[0])
+	}
 	if cfg.DeploymentCfg.Kubernetes.SchedulePodsOnMasters {
-		os.RunCommandOn("kubectl taint nodes --all node-role.kubernetes.io/master- || true",
-			firstMasterNode.IP, false)
-		os.RunCommandOn("kubectl taint nodes --all node-role.kubernetes.io/control-plane- || true",
-			firstMasterNode.IP, false)
+		for _, masterNode := range nodes.GetMasterKubeNodes() {
+			os.RunCommandOn(fmt.Sprintf("kubectl taint node %s node-role.kubernetes.io/master- || true",
+				masterNode.Hostname), firstMasterNode.IP, false)
+			os.RunCommandOn(fmt.Sprintf("kubectl taint node %s node-role.kubernetes.io/control-plane- || true",
+				masterNode.Hostname), firstMasterNode.IP, false)
+		}
+	}
+	// taints defined in deployment config are applied after removing default ones, so they are kept
+	util.StartSpinner("Applying node labels and taints")
+	for _, node := range nodes.Nodes {
+		kube.ApplyNodeSettings(firstMasterNode.IP, node.Hostname, cfg.DeploymentCfg.GetNodeSettings(node))
 	}
+	util.StopSpinner("", logsymbols.Success)
 	time.Sleep(10 * time.Second)
 	for _, node := range nodes.Nodes {
 		os.RunCommandOn("sudo systemctl restart containerd", node.IP, true)
 	}
 	time.Sleep(10 * time.Second)
-	kube.WaitUntilPodsRunning([]string{"kube-system"})
+	cni := cfg.DeploymentCfg.Kubernetes.CNI
+	if cni.GetPlugin() == model.CNIPluginNone && !cni.HasHooks() {
+		// coredns waits for a network plugin installed after tkube
+		kube.WaitUntilPodsRunningExcept([]string{"kube-system"}, []string{"coredns-"})
+	} else {
+		kube.WaitUntilPodsRunning([]string{"kube-system"})
+	}
+}
+
+func kubeSysctlConf() string {
+	kubeConf := "net.bridge.bridge-nf-call-ip6tables = 1\nnet.bridge.bridge-nf-call-iptables = 1\nnet.ipv4.ip_forward = 1\n"
+	if cfg.DeploymentCfg.IPv6Enabled() {
+		kubeConf += "net.ipv6.conf.all.forwarding = 1\n"
+	}
+	return kubeConf
+}
+
+// joinNode copies the JoinConfiguration of node and joins it to the cluster. The file holds the bootstrap token and
+// certificate key, so only root can read it and it is removed whether the join succeeds or not.
+func joinNode(node model.KubeNode, joinInfo kube.JoinInfo) {
+	joinCfgFile := fmt.Sprintf("%s/%s", path.GetTKubeTmpDir(node.IP), constant.KubeadmJoinCfgFile)
+	os.CreateSecretFile(kube.CreateKubeadmJoinCfg(KubeVersion, node, joinInfo, cfg.DeploymentCfg), joinCfgFile,
+		node.IP)
+	patches := kube.NodePatches(cfg.DeploymentCfg, node)
+	kube.WritePatches(node.IP, patches)
+	os.RunCommandOn(fmt.Sprintf("sudo kubeadm join --config %[1]s%[2]s; rc=$?; sudo rm -f %[1]s; exit $rc",
+		joinCfgFile, kube.PatchesFlag(KubeVersion, patches)), node.IP, true)
 }
 
 func kubeSystemPodNames(nodeName string) []string {
@@ -1081,37 +1238,8 @@ func kubeSystemPodNames(nodeName string) []string {
 
 func getCalicoVersion() string {
 	if CalicoVersion == "auto" {
-		kubeSemVer, _ := version.NewVersion(KubeVersion)
-		kube132Ver, _ := version.NewVersion("1.32")
-		kube127Ver, _ := version.NewVersion("1.27")
-		kube124Ver, _ := version.NewVersion("1.24")
-		kube123Ver, _ := version.NewVersion("1.23")
-		kube122Ver, _ := version.NewVersion("1.22")
-		kube121Ver, _ := version.NewVersion("1.21")
-		kube120Ver, _ := version.NewVersion("1.20")
-		kube119Ver, _ := version.NewVersion("1.19")
-		kube118Ver, _ := version.NewVersion("1.18")
-		if kubeSemVer.GreaterThanOrEqual(kube132Ver) {
-			return "3.31.2"
-		} else if kubeSemVer.GreaterThanOrEqual(kube127Ver) {
-			return "3.27.2"
-		} else if kubeSemVer.GreaterThanOrEqual(kube124Ver) {
-			return "3.26.4"
-		} else if kubeSemVer.GreaterThanOrEqual(kube123Ver) {
-			return "3.25.2"
-		} else if kubeSemVer.GreaterThanOrEqual(kube122Ver) {
-			return "3.24.6"
-		} else if kubeSemVer.GreaterThanOrEqual(kube121Ver) {
-			return "3.23"
-		} else if kubeSemVer.GreaterThanOrEqual(kube120Ver) {
-			return "3.21"
-		} else if kubeSemVer.GreaterThanOrEqual(kube119Ver) {
-			return "3.20"
-		} else if kubeSemVer.GreaterThanOrEqual(kube118Ver) {
-			return "3.18"
-		} else { // kube version between 1.17 and 1.18
-			return "3.17"
-		}
+		release, _ := compat.Lookup(KubeVersion)
+		return release.Calico
 	} else {
 		return CalicoVersion
 	}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	cfg "com.github.tunahansezen/tkube/pkg/config"
+	"com.github.tunahansezen/tkube/pkg/config/compat"
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	conn "com.github.tunahansezen/tkube/pkg/connection"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"com.github.tunahansezen/tkube/pkg/util"
+	"github.com/guumaster/logsymbols"
+	"github.com/hashicorp/go-version"
+)
+
+const (
+	ComponentKubernetes = "kubernetes"
+	ComponentDocker     = "docker"
+	ComponentContainerd = "containerd"
+	ComponentEtcd       = "etcd"
+	ComponentCalico     = "calico"
+	ComponentFlannel    = "flannel"
+	ComponentHelm       = "helm"
+	ComponentHelmfile   = "helmfile"
+	// LatestKubeVersion is resolved to the newest patch of the newest kubernetes version in the compatibility matrix
+	LatestKubeVersion = "latest"
+)
+
+var (
+	// versionOverrides are the components whose versions are given explicitly with flags or the iso file
+	versionOverrides  = make(map[string]bool)
+	minorVersionRegex = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+)
+
+type componentVersion struct {
+	component string
+	value     *string
+	recorded  *string
+}
+
+func componentVersions() []componentVersion {
+	versions := &cfg.DeploymentCfg.Versions
+	return []componentVersion{
+		{ComponentKubernetes, &KubeVersion, &versions.Kubernetes},
+		{ComponentDocker, &DockerVersion, &versions.Docker},
+		{ComponentContainerd, &ContainerdVersion, &versions.Containerd},
+		{ComponentEtcd, &EtcdVersion, &versions.Etcd},
+		{ComponentCalico, &CalicoVersion, &versions.Calico},
+		{ComponentFlannel, &FlannelVersion, &versions.Flannel},
+		{ComponentHelm, &HelmVersion, &versions.Helm},
+		{ComponentHelmfile, &HelmfileVersion, &versions.Helmfile},
+	}
+}
+
+func SetVersionOverride(component string) {
+	versionOverrides[component] = true
+}
+
+// defaultVersions returns the components which use neither an override nor a recorded version.
+func defaultVersions() map[string]bool {
+	defaults := make(map[string]bool)
+	for _, v := range componentVersions() {
+		if !versionOverrides[v.component] && *v.recorded == "" {
+			defaults[v.component] = true
+		}
+	}
+	return defaults
+}
+
+// resolveVersions uses the versions recorded in deployment config, so nodes added or recovered later get the
+// versions the cluster runs. Overrides win, with a warning if they differ from the recorded ones.
+func resolveVersions() {
+	for _, v := range componentVersions() {
+		if *v.recorded == "" {
+			continue
+		}
+		if !versionOverrides[v.component] {
+			*v.value = *v.recorded
+		} else if *v.value != *v.recorded {
+			util.PrintWarning(fmt.Sprintf("%s version \"%s\" differs from \"%s\" recorded for the cluster",
+				v.component, *v.value, *v.recorded))
+		}
+	}
+}
+
+// kubeVersionMatches reports whether the exact version v satisfies the kubernetes version requested with --kube, a
+// minor version like "1.34" is satisfied by all of its patches.
+func kubeVersionMatches(requested, v string) bool {
+	return requested == LatestKubeVersion || v == requested || strings.HasPrefix(v, requested+".")
+}
+
+// resolveKubeVersion resolves a minor or "latest" kubernetes version to the newest patch found in the kube repo of
+// the first node.
+func resolveKubeVersion() {
+	requested := KubeVersion
+	if requested != LatestKubeVersion && !minorVersionRegex.MatchString(requested) {
+		return
+	}
+	firstNode := cfg.DeploymentCfg.GetKubeNodes()[0]
+	err := conn.CheckSSHConnection(&conn.Node{IP: firstNode.IP, Hostname: firstNode.Hostname, SSHPort: 22})
+	os.ThrowIfError(err, 1)
+	os.PreparePrivilegeEscalation(firstNode.IP)
+	if cfg.DeploymentCfg.Kubernetes.Repo.Enabled {
+		minorVersion := requested
+		if requested == LatestKubeVersion {
+			minorVersion = compat.Get().Kubernetes[0].Version
+		}
+		addKubeRepo(minorVersion, firstNode.IP)
+	}
+	util.StartSpinner(fmt.Sprintf("Resolving kubernetes version \"%s\" on \"%s\"", requested, firstNode.Hostname))
+	var latest *version.Version
+	for _, v := range os.AvailablePackageVersions("kubeadm", firstNode.IP) {
+		semVer, err := version.NewVersion(v)
+		if err != nil || !kubeVersionMatches(requested, v) {
+			continue
+		}
+		if latest == nil || semVer.GreaterThan(latest) {
+			latest = semVer
+		}
+	}
+	if latest == nil {
+		os.Exit(fmt.Sprintf("No kubernetes version matching \"%s\" was found in the repos of \"%s\"", requested,
+			firstNode.Hostname), 1)
+	}
+	KubeVersion = latest.String()
+	util.StopSpinner(fmt.Sprintf("Kubernetes version \"%s\" resolved to \"%s\"", requested, KubeVersion),
+		logsymbols.Success)
+}
+
+// resolveAutoVersions resolves "auto" network plugin versions from the compatibility matrix, so the versions
+// validated and recorded belong to the resolved kubernetes version.
+func resolveAutoVersions() {
+	CalicoVersion = getCalicoVersion()
+	FlannelVersion = getFlannelVersion()
+}
+
+// recordVersions writes the versions installed on nodes to deployment config, "auto" versions are resolved before.
+// Every run installing nodes records them, so overrides given while adding or recovering nodes are kept as well. ip
+// is one of the installed nodes.
+func recordVersions(ip net.IP) {
+	for _, v := range componentVersions() {
+		*v.recorded = *v.value
+	}
+	versions := &cfg.DeploymentCfg.Versions
+	// only the version of the installed network plugin is kept
+	versions.Calico, versions.Flannel = "", ""
+	switch cfg.DeploymentCfg.Kubernetes.CNI.GetPlugin() {
+	case model.CNIPluginCalico:
+		versions.Calico = getCalicoVersion()
+	case model.CNIPluginFlannel:
+		versions.Flannel = getFlannelVersion()
+	}
+	if ContainerdVersion == DefaultContainerdVersion {
+		installed, containerdVersion := os.PackageInstalledOn("containerd.io", ip)
+		if installed {
+			versions.Containerd = containerdVersion
+		} else {
+			versions.Containerd = ""
+		}
+	}
+	err := cfg.SaveDeploymentConfig()
+	if err != nil {
+		os.Exit(err.Error(), 1)
+	}
+}
@@ -1,19 +1,85 @@
 package encryption
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"embed"
+	"errors"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltLen = 16
+	keyLen  = 32
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
 )
 
 var (
 	//go:embed resources
-	f        embed.FS
-	encBytes = []byte{35, 46, 57, 24, 85, 35, 24, 74, 87, 35, 88, 98, 66, 32, 14, 05}
+	f         embed.FS
+	encBytes  = []byte{35, 46, 57, 24, 85, 35, 24, 74, 87, 35, 88, 98, 66, 32, 14, 05}
+	header    = []byte("TKUBE-ENC-V1\n")
+	ErrDecode = errors.New("encrypted data is corrupted or passphrase is wrong")
 )
 
-func EncryptFile(plainText []byte) ([]byte, error) {
-	key, err := f.ReadFile("resources/aes.key")
+// Encrypt seals plainText with AES-GCM. The key is derived from passphrase with scrypt using a random salt,
+// and a random nonce is used for every call. Output layout: header | salt | nonce | ciphertext.
+func Encrypt(plainText []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(header)+len(salt)+len(nonce)+len(plainText)+gcm.Overhead())
+	out = append(out, header...)
+	out = append(out, salt...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, plainText, header), nil
+}
+
+func Decrypt(data []byte, passphrase string) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, ErrDecode
+	}
+	data = data[len(header):]
+	if len(data) < saltLen {
+		return nil, ErrDecode
+	}
+	salt := data[:saltLen]
+	gcm, err := newGCM(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltLen:]
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrDecode
+	}
+	plainText, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], header)
+	if err != nil {
+		return nil, ErrDecode
+	}
+	return plainText, nil
+}
+
+// IsEncrypted reports whether data was produced by Encrypt. Anything else is treated as a legacy file.
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, header)
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLen)
 	if err != nil {
 		return nil, err
 	}
@@ -21,13 +87,12 @@ func EncryptFile(plainText []byte) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	cfb := cipher.NewCFBEncrypter(block, encBytes)
-	cipherText := make([]byte, len(plainText))
-	cfb.XORKeyStream(cipherText, plainText)
-	return cipherText, nil
+	return cipher.NewGCM(block)
 }
 
-func DecryptFile(cipherText []byte) ([]byte, error) {
+// DecryptLegacy decrypts files written by older tkube versions with the embedded static key.
+// It is only kept to migrate those files to the passphrase based format.
+func DecryptLegacy(cipherText []byte) ([]byte, error) {
 	key, err := f.ReadFile("resources/aes.key")
 	if err != nil {
 		return nil, err
@@ -0,0 +1,38 @@
+package encryption
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryption_RoundTrip(t *testing.T) {
+	plainText := []byte("192.168.50.10:\n  sshUser: vagrant\n  sshPass: vagrant\n")
+	first, err := Encrypt(plainText, "passphrase")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	second, err := Encrypt(plainText, "passphrase")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if bytes.Equal(first, second) {
+		t.Error("two encryptions of the same data must differ")
+	}
+	if !IsEncrypted(first) {
+		t.Error("encrypted data must be detected")
+	}
+	decrypted, err := Decrypt(first, "passphrase")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if !bytes.Equal(decrypted, plainText) {
+		t.Errorf("decrypted data mismatch: %s", decrypted)
+	}
+	if _, err = Decrypt(first, "wrong"); err == nil {
+		t.Error("decryption with wrong passphrase must fail")
+	}
+	first[len(first)-1] ^= 0xff
+	if _, err = Decrypt(first, "passphrase"); err == nil {
+		t.Error("decryption of tampered data must fail")
+	}
+}
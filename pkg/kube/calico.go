@@ -0,0 +1,154 @@
+package kube
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/constant"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// PatchCalicoManifest sets pools, encapsulation, MTU, address detection and env vars of deployment config to
+// calico-node and rewrites docker.io images to the image registry.
+func PatchCalicoManifest(data []byte, dc model.DeploymentConfig) ([]byte, error) {
+	m, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	configMaps := m.resources("ConfigMap", "calico-config")
+	daemonSets := m.resources("DaemonSet", "calico-node")
+	if len(configMaps) != 1 || len(daemonSets) != 1 {
+		return nil, fmt.Errorf("calico config map and calico-node daemonset not found in manifest")
+	}
+	calico := dc.Kubernetes.Calico
+	configData := nodeValue(configMaps[0], "data")
+	if calico.MTU > 0 {
+		vethMtu := nodeValue(configData, "veth_mtu")
+		if vethMtu.Kind != yamlv3.ScalarNode {
+			return nil, fmt.Errorf("veth_mtu not found in calico config map")
+		}
+		vethMtu.Value = strconv.Itoa(calico.MTU)
+	}
+	nodeValue(configData, "calico_backend").Value = calico.GetBackend()
+	if dc.IPv6Enabled() {
+		// calico-ipam assigns only IPv4 addresses unless it is told otherwise
+		cniConfig := nodeValue(configData, "cni_network_config")
+		cniConfig.Value = strings.Replace(cniConfig.Value, `"type": "calico-ipam"`, fmt.Sprintf(
+			`"type": "calico-ipam", "assign_ipv4": "%t", "assign_ipv6": "true"`, dc.DualStack()), 1)
+	}
+	calicoNode := findContainer(daemonSets[0], "calico-node")
+	if calicoNode == nil {
+		return nil, fmt.Errorf("calico-node container not found in manifest")
+	}
+	for _, env := range append(dc.GetCalicoNetworkEnvVars(), calico.EnvVars...) {
+		name, value, _ := strings.Cut(env, "=")
+		setContainerEnv(calicoNode, name, value)
+	}
+	m.pinNodeInterfaces(daemonSets[0], dc.GetNodeInterfaces(), dc.GetSharedNodeInterface() != "",
+		func(daemonSet *yamlv3.Node, iface string) {
+			// env vars of deployment config still override address detection
+			for _, env := range append(dc.GetCalicoAutodetectionEnvVars(iface), calico.EnvVars...) {
+				name, value, _ := strings.Cut(env, "=")
+				setContainerEnv(findContainer(daemonSet, "calico-node"), name, value)
+			}
+		})
+	if calico.GetBackend() != "bird" {
+		// probes of bird fail when BGP is not running
+		for _, probe := range []string{"livenessProbe", "readinessProbe"} {
+			command := nodeValue(calicoNode, probe, "exec", "command")
+			command.Content = removeScalars(command.Content, "-bird-live", "-bird-ready")
+		}
+	}
+	if dc.Kubernetes.ImageRegistry != constant.DefaultKubeImageRegistry {
+		m.rewriteImages("docker.io", dc.Kubernetes.ImageRegistry)
+	}
+	return m.Marshal()
+}
+
+// PatchTigeraOperatorManifest rewrites the operator image to the image registry.
+func PatchTigeraOperatorManifest(data []byte, dc model.DeploymentConfig) ([]byte, error) {
+	if dc.Kubernetes.ImageRegistry == constant.DefaultKubeImageRegistry {
+		return data, nil
+	}
+	m, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	m.rewriteImages("quay.io", dc.Kubernetes.ImageRegistry)
+	return m.Marshal()
+}
+
+type calicoInstallation struct {
+	ApiVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Metadata   map[string]string      `yaml:"metadata"`
+	Spec       calicoInstallationSpec `yaml:"spec"`
+}
+
+type calicoInstallationSpec struct {
+	Registry      string        `yaml:"registry,omitempty"`
+	CalicoNetwork calicoNetwork `yaml:"calicoNetwork"`
+}
+
+type calicoNetwork struct {
+	Bgp                        string               `yaml:"bgp"`
+	MTU                        int                  `yaml:"mtu,omitempty"`
+	IPPools                    []calicoIPPool       `yaml:"ipPools"`
+	NodeAddressAutodetectionV4 *calicoAutodetection `yaml:"nodeAddressAutodetectionV4,omitempty"`
+	NodeAddressAutodetectionV6 *calicoAutodetection `yaml:"nodeAddressAutodetectionV6,omitempty"`
+}
+
+type calicoIPPool struct {
+	Cidr          string `yaml:"cidr"`
+	Encapsulation string `yaml:"encapsulation"`
+	NatOutgoing   string `yaml:"natOutgoing"`
+	NodeSelector  string `yaml:"nodeSelector"`
+}
+
+type calicoAutodetection struct {
+	Interface string `yaml:"interface"`
+}
+
+// CreateCalicoInstallation returns the Installation of the tigera operator with the calico settings of deployment
+// config.
+func CreateCalicoInstallation(dc model.DeploymentConfig) []byte {
+	calico := dc.Kubernetes.Calico
+	network := calicoNetwork{Bgp: "Enabled", MTU: calico.MTU}
+	if calico.GetBackend() != "bird" {
+		network.Bgp = "Disabled"
+	}
+	if cidr := dc.GetPodCIDR(false); cidr != "" {
+		network.IPPools = append(network.IPPools, calicoIPPool{Cidr: cidr, Encapsulation: calico.GetEncapsulation(),
+			NatOutgoing: "Enabled", NodeSelector: "all()"})
+	}
+	if cidr := dc.GetPodCIDR(true); cidr != "" {
+		network.IPPools = append(network.IPPools, calicoIPPool{Cidr: cidr,
+			Encapsulation: calico.GetIPv6Encapsulation(), NatOutgoing: "Enabled", NodeSelector: "all()"})
+	}
+	if iface := dc.GetSharedNodeInterface(); iface != "" {
+		regex := model.GetCalicoAutodetectionRegex(iface)
+		if dc.GetPodCIDR(false) != "" {
+			network.NodeAddressAutodetectionV4 = &calicoAutodetection{Interface: regex}
+		}
+		if dc.IPv6Enabled() {
+			network.NodeAddressAutodetectionV6 = &calicoAutodetection{Interface: regex}
+		}
+	}
+	installation := calicoInstallation{
+		ApiVersion: "operator.tigera.io/v1",
+		Kind:       "Installation",
+		Metadata:   map[string]string{"name": "default"},
+		Spec:       calicoInstallationSpec{CalicoNetwork: network},
+	}
+	if dc.Kubernetes.ImageRegistry != constant.DefaultKubeImageRegistry {
+		installation.Spec.Registry = strings.TrimSuffix(dc.Kubernetes.ImageRegistry, "/") + "/"
+	}
+	var b bytes.Buffer
+	yamlEncoder := yamlv3.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	yamlEncoder.Encode(&installation)
+	return b.Bytes()
+}
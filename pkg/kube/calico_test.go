@@ -0,0 +1,114 @@
+package kube
+
+import (
+	"net"
+	goos "os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+)
+
+func TestPatchCalicoManifest(t *testing.T) {
+	data, err := goos.ReadFile(filepath.Join("testdata", "calico.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := testDeploymentConfig()
+	dc.Kubernetes.ImageRegistry = "registry.example.com"
+	dc.Kubernetes.Calico = model.Calico{
+		EnvVars:       []string{"FELIX_LOGSEVERITYSCREEN=info", "CALICO_IPV4POOL_VXLAN=CrossSubnet"},
+		Encapsulation: model.CalicoEncapsulationVXLAN,
+		MTU:           1450,
+	}
+	dc.Nodes[0].Interface = "eth1"
+	if err = dc.ValidateCNI(); err != nil {
+		t.Fatal(err)
+	}
+	actual, err := PatchCalicoManifest(data, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "calico.golden", actual)
+}
+
+func TestPatchCalicoManifestAddedNode(t *testing.T) {
+	data, err := goos.ReadFile(filepath.Join("testdata", "calico.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dc := testDeploymentConfig()
+	for i := range dc.Nodes {
+		dc.Nodes[i].Interface = "eth1"
+	}
+	actual, err := PatchCalicoManifest(data, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(actual), "kind: DaemonSet") != 1 || !strings.Contains(string(actual), "interface=^eth1$") {
+		t.Fatal("expected a single calico-node daemonset pinned to eth1")
+	}
+	// the manifest is applied again when a node with another interface is added
+	dc.Nodes = append(dc.Nodes, model.KubeNode{Hostname: "worker2", IP: net.ParseIP("192.168.50.21"),
+		Interface: "ens192", KubeType: "worker"})
+	actual, err = PatchCalicoManifest(data, dc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"name: calico-node-ens192", "tkube.io/network-interface: ens192",
+		"interface=^ens192$", "operator: DoesNotExist"} {
+		if !strings.Contains(string(actual), expected) {
+			t.Errorf("expected \"%s\" in calico manifest with the added node", expected)
+		}
+	}
+	labels := KubeletExtraArgs(dc.Nodes[len(dc.Nodes)-1], dc)["node-labels"]
+	if !strings.Contains(labels, "tkube.io/network-interface=ens192") {
+		t.Errorf("expected the added node to be labeled with its interface, got \"%s\"", labels)
+	}
+}
+
+func TestCreateCalicoInstallation(t *testing.T) {
+	dc := testDeploymentConfig()
+	dc.Kubernetes.PodSubnet = "10.244.0.0/16,fd00:10:244::/56"
+	dc.Kubernetes.ServiceSubnet = "10.96.0.0/12,fd00:10:96::/112"
+	dc.Kubernetes.ImageRegistry = "registry.example.com"
+	dc.Kubernetes.Calico = model.Calico{
+		Mode:          model.CalicoModeOperator,
+		Encapsulation: model.CalicoEncapsulationVXLANCross,
+		MTU:           1450,
+	}
+	for i := range dc.Nodes {
+		dc.Nodes[i].Interface = "eth1"
+	}
+	if err := dc.ValidateCNI(); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "calico-installation.golden", CreateCalicoInstallation(dc))
+	dc.Nodes[1].Interface = "ens192"
+	if err := dc.ValidateCNI(); err == nil {
+		t.Error("expected error for operator mode with different interfaces")
+	}
+}
+
+func TestReplaceImageRegistry(t *testing.T) {
+	tests := []struct {
+		image, expected string
+	}{
+		{"docker.io/calico/node:v3.31.2", "registry.example.com/calico/node:v3.31.2"},
+		{"calico/node:v3.31.2", "registry.example.com/calico/node:v3.31.2"},
+		{"nginx", "registry.example.com/nginx"},
+		{"quay.io/tigera/operator:v1.38.0", "quay.io/tigera/operator:v1.38.0"},
+		{"localhost:5000/docker.io/node", "localhost:5000/docker.io/node"},
+	}
+	for _, test := range tests {
+		if actual := replaceImageRegistry(test.image, "docker.io", "registry.example.com/"); actual != test.expected {
+			t.Errorf("replaceImageRegistry(%s) = %s, expected %s", test.image, actual, test.expected)
+		}
+	}
+	image := "ghcr.io/flannel-io/flannel-cni-plugin:v1.6.2-flannel1"
+	if actual := replaceImageRegistry(image, "", "registry.example.com"); actual !=
+		"registry.example.com/flannel-io/flannel-cni-plugin:v1.6.2-flannel1" {
+		t.Errorf("replaceImageRegistry(%s) of any registry = %s", image, actual)
+	}
+}
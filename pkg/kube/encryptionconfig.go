@@ -0,0 +1,122 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// EncryptionCfg is the EncryptionConfiguration of apiservers. Every key has its own provider entry, the first one
+// encrypts and all of them decrypt. identity is kept last, so secrets written before encryption can still be read.
+type EncryptionCfg struct {
+	ApiVersion string               `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string               `yaml:"kind" json:"kind"`
+	Resources  []encryptionResource `yaml:"resources" json:"resources"`
+}
+
+type encryptionResource struct {
+	Resources []string             `yaml:"resources" json:"resources"`
+	Providers []EncryptionProvider `yaml:"providers" json:"providers"`
+}
+
+type EncryptionProvider struct {
+	AESCBC    *encryptionKeys `yaml:"aescbc,omitempty" json:"aescbc,omitempty"`
+	Secretbox *encryptionKeys `yaml:"secretbox,omitempty" json:"secretbox,omitempty"`
+	Identity  *struct{}       `yaml:"identity,omitempty" json:"identity,omitempty"`
+}
+
+type encryptionKeys struct {
+	Keys []encryptionKey `yaml:"keys" json:"keys"`
+}
+
+type encryptionKey struct {
+	Name   string `yaml:"name" json:"name"`
+	Secret string `yaml:"secret" json:"secret"`
+}
+
+// NewEncryptionCfg returns a config encrypting secrets with a new key of provider.
+func NewEncryptionCfg(provider string) (EncryptionCfg, error) {
+	p, err := newEncryptionProvider(provider)
+	if err != nil {
+		return EncryptionCfg{}, err
+	}
+	return EncryptionCfg{
+		ApiVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "EncryptionConfiguration",
+		Resources: []encryptionResource{{
+			Resources: []string{"secrets"},
+			Providers: []EncryptionProvider{p, {Identity: &struct{}{}}},
+		}},
+	}, nil
+}
+
+func ParseEncryptionCfg(data []byte) (EncryptionCfg, error) {
+	var ec EncryptionCfg
+	err := yamlv3.Unmarshal(data, &ec)
+	if err != nil {
+		return ec, err
+	}
+	if ec.Kind != "EncryptionConfiguration" || len(ec.Resources) != 1 || len(ec.Resources[0].Providers) < 2 {
+		return ec, errors.New("encryption config is not the one written by tkube")
+	}
+	return ec, nil
+}
+
+func (ec EncryptionCfg) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	yamlEncoder := yamlv3.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	err := yamlEncoder.Encode(&ec)
+	return b.Bytes(), err
+}
+
+// AddKey adds a new key of provider after the current ones. Apiservers can decrypt with it, but do not use it for
+// encryption yet, so it can be rolled out master by master.
+func (ec *EncryptionCfg) AddKey(provider string) error {
+	p, err := newEncryptionProvider(provider)
+	if err != nil {
+		return err
+	}
+	providers := ec.Resources[0].Providers
+	last := len(providers) - 1
+	ec.Resources[0].Providers = append(providers[:last:last], p, providers[last])
+	return nil
+}
+
+// PromoteNewestKey makes the key added last the one used for encryption.
+func (ec *EncryptionCfg) PromoteNewestKey() {
+	providers := ec.Resources[0].Providers
+	newest := providers[len(providers)-2]
+	copy(providers[1:len(providers)-1], providers[:len(providers)-2])
+	providers[0] = newest
+}
+
+// DropOldKeys keeps only the key used for encryption and identity.
+func (ec *EncryptionCfg) DropOldKeys() {
+	providers := ec.Resources[0].Providers
+	ec.Resources[0].Providers = []EncryptionProvider{providers[0], providers[len(providers)-1]}
+}
+
+func newEncryptionProvider(provider string) (EncryptionProvider, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return EncryptionProvider{}, err
+	}
+	keys := &encryptionKeys{Keys: []encryptionKey{{
+		Name:   fmt.Sprintf("key-%d", time.Now().UnixNano()),
+		Secret: base64.StdEncoding.EncodeToString(secret),
+	}}}
+	switch provider {
+	case model.EncryptionProviderAESCBC:
+		return EncryptionProvider{AESCBC: keys}, nil
+	case model.EncryptionProviderSecretbox:
+		return EncryptionProvider{Secretbox: keys}, nil
+	}
+	return EncryptionProvider{}, fmt.Errorf("unknown encryption provider \"%s\"", provider)
+}
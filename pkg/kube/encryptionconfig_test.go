@@ -0,0 +1,68 @@
+package kube
+
+import (
+	"strings"
+	"testing"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+)
+
+func TestEncryptionCfgRotation(t *testing.T) {
+	ec, err := NewEncryptionCfg(model.EncryptionProviderAESCBC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := ec.Resources[0].Providers[0]
+	if err = ec.AddKey(model.EncryptionProviderSecretbox); err != nil {
+		t.Fatal(err)
+	}
+	providers := ec.Resources[0].Providers
+	if len(providers) != 3 || providers[0] != oldKey || providers[1].Secretbox == nil || providers[2].Identity == nil {
+		t.Fatalf("new key must be added after the current one: %+v", providers)
+	}
+	newKey := providers[1]
+
+	ec.PromoteNewestKey()
+	providers = ec.Resources[0].Providers
+	if providers[0] != newKey || providers[1] != oldKey || providers[2].Identity == nil {
+		t.Fatalf("new key must be used for encryption: %+v", providers)
+	}
+
+	data, err := ec.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err = ParseEncryptionCfg(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec.DropOldKeys()
+	providers = ec.Resources[0].Providers
+	if len(providers) != 2 || providers[0].Secretbox == nil ||
+		providers[0].Secretbox.Keys[0] != newKey.Secretbox.Keys[0] || providers[1].Identity == nil {
+		t.Fatalf("only the new key and identity must be kept: %+v", providers)
+	}
+}
+
+func TestParseEncryptionCfg(t *testing.T) {
+	ec, err := NewEncryptionCfg(model.EncryptionProviderAESCBC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ec.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the config is read back from masters with the trailing newline trimmed
+	parsed, err := ParseEncryptionCfg([]byte(strings.TrimSuffix(string(data), "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Resources) != 1 || parsed.Resources[0].Providers[0].AESCBC == nil ||
+		parsed.Resources[0].Providers[0].AESCBC.Keys[0] != ec.Resources[0].Providers[0].AESCBC.Keys[0] {
+		t.Errorf("keys must be kept when the config is read back: %+v", parsed.Resources)
+	}
+	if _, err := ParseEncryptionCfg([]byte("apiVersion: v1\nkind: Secret\n")); err == nil {
+		t.Error("expected error for a file which is not an encryption config")
+	}
+}
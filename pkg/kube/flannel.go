@@ -0,0 +1,70 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// FlannelSettings are applied to the flannel manifest before it is installed.
+type FlannelSettings struct {
+	Network     string
+	IPv6Network string
+	Backend     string
+	// Interfaces are the distinct interfaces of the nodes, each node uses its own one
+	Interfaces []string
+	// InterfaceShared is true if all nodes have the single interface in Interfaces
+	InterfaceShared bool
+	// ImageRegistry replaces the registries of all flannel images, if it is not empty
+	ImageRegistry string
+}
+
+// PatchFlannelManifest writes pod networks and backend to net-conf.json of flannel, pins flannel to the interface of
+// each node and rewrites image registries.
+func PatchFlannelManifest(data []byte, s FlannelSettings) ([]byte, error) {
+	m, err := parseManifest(data)
+	if err != nil {
+		return nil, err
+	}
+	configMaps := m.resources("ConfigMap", "kube-flannel-cfg")
+	daemonSets := m.resources("DaemonSet", "")
+	if len(configMaps) != 1 || len(daemonSets) == 0 {
+		return nil, fmt.Errorf("flannel config map and daemonset not found in manifest")
+	}
+	netConfNode := nodeValue(configMaps[0], "data", "net-conf.json")
+	netConf := make(map[string]interface{})
+	if err = json.Unmarshal([]byte(netConfNode.Value), &netConf); err != nil {
+		return nil, fmt.Errorf("invalid flannel net-conf.json: %w", err)
+	}
+	delete(netConf, "Network")
+	if s.Network != "" {
+		netConf["Network"] = s.Network
+	} else {
+		netConf["EnableIPv4"] = false
+	}
+	if s.IPv6Network != "" {
+		netConf["EnableIPv6"] = true
+		netConf["IPv6Network"] = s.IPv6Network
+	}
+	netConf["Backend"] = map[string]string{"Type": s.Backend}
+	netConfData, err := json.MarshalIndent(netConf, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	netConfNode.Value = string(netConfData) + "\n"
+	netConfNode.Style = yamlv3.LiteralStyle
+	for _, daemonSet := range daemonSets {
+		if findContainer(daemonSet, "kube-flannel") == nil {
+			continue
+		}
+		m.pinNodeInterfaces(daemonSet, s.Interfaces, s.InterfaceShared, func(daemonSet *yamlv3.Node, iface string) {
+			args := nodeValue(findContainer(daemonSet, "kube-flannel"), "args")
+			args.Content = append(args.Content, scalarNode(fmt.Sprintf("--iface=%s", iface)))
+		})
+	}
+	if s.ImageRegistry != "" {
+		m.rewriteImages("", s.ImageRegistry)
+	}
+	return m.Marshal()
+}
@@ -0,0 +1,54 @@
+package kube
+
+import (
+	goos "os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPatchFlannelManifest(t *testing.T) {
+	data, err := goos.ReadFile(filepath.Join("testdata", "kube-flannel.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := PatchFlannelManifest(data, FlannelSettings{
+		Network:       "10.244.0.0/16",
+		IPv6Network:   "fd00:10:244::/56",
+		Backend:       "host-gw",
+		Interfaces:    []string{"eth1", "ens192"},
+		ImageRegistry: "registry.example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "flannel.golden", actual)
+}
+
+func TestPatchFlannelManifestInvalid(t *testing.T) {
+	if _, err := PatchFlannelManifest([]byte("kind: Namespace\n"), FlannelSettings{}); err == nil {
+		t.Error("expected error for manifest without flannel config map")
+	}
+}
+
+func TestPatchFlannelManifestSharedInterface(t *testing.T) {
+	data, err := goos.ReadFile(filepath.Join("testdata", "kube-flannel.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := PatchFlannelManifest(data, FlannelSettings{
+		Network:         "10.244.0.0/16",
+		Backend:         "vxlan",
+		Interfaces:      []string{"eth1"},
+		InterfaceShared: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := strings.Count(string(actual), "kind: DaemonSet"); count != 1 {
+		t.Errorf("expected a single daemonset for a shared interface, got %d", count)
+	}
+	if !strings.Contains(string(actual), "- --iface=eth1") || strings.Contains(string(actual), "nodeSelector") {
+		t.Error("expected flannel pinned to eth1 without node selector")
+	}
+}
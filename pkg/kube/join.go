@@ -0,0 +1,136 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/constant"
+	"com.github.tunahansezen/tkube/pkg/os"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+const (
+	caCertPath         = "/etc/kubernetes/pki/ca.crt"
+	bootstrapTokenTTL  = "2h"
+	bootstrapTokenChar = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+type KubeadmJoinCfg struct {
+	ApiVersion       string            `yaml:"apiVersion" json:"apiVersion"`
+	Kind             string            `yaml:"kind" json:"kind"`
+	Discovery        discovery         `yaml:"discovery" json:"discovery"`
+	NodeRegistration *nodeRegistration `yaml:"nodeRegistration,omitempty" json:"nodeRegistration,omitempty"`
+	ControlPlane     *joinControlPlane `yaml:"controlPlane,omitempty" json:"controlPlane,omitempty"`
+}
+
+type discovery struct {
+	BootstrapToken bootstrapTokenDiscovery `yaml:"bootstrapToken" json:"bootstrapToken"`
+}
+
+type bootstrapTokenDiscovery struct {
+	ApiServerEndpoint string   `yaml:"apiServerEndpoint" json:"apiServerEndpoint"`
+	Token             string   `yaml:"token" json:"token"`
+	CACertHashes      []string `yaml:"caCertHashes" json:"caCertHashes"`
+}
+
+type joinControlPlane struct {
+	LocalApiEndpoint *localApiEndpoint `yaml:"localAPIEndpoint" json:"localAPIEndpoint"`
+	CertificateKey   string            `yaml:"certificateKey" json:"certificateKey"`
+}
+
+// JoinInfo is what nodes need to join the cluster.
+type JoinInfo struct {
+	ApiServerEndpoint string
+	Token             string
+	CACertHash        string
+	// CertificateKey decrypts the control plane certificates uploaded by kubeadm, only masters use it
+	CertificateKey string
+}
+
+// CreateJoinInfo creates a bootstrap token on the master and reads the CA cert hash of the cluster.
+func CreateJoinInfo(masterIP net.IP, apiServerEndpoint string, certKey string) JoinInfo {
+	token, err := generateBootstrapToken()
+	os.ThrowIfError(err, 1)
+	os.RunCommandOn(fmt.Sprintf("sudo kubeadm token create %s --ttl %s --description \"tkube join\"", token,
+		bootstrapTokenTTL), masterIP, true)
+	caCert, err := os.ReadFile(caCertPath, masterIP)
+	os.ThrowIfError(err, 1)
+	hash, err := caCertHash(caCert)
+	os.ThrowIfError(err, 1)
+	return JoinInfo{
+		ApiServerEndpoint: apiServerEndpoint,
+		Token:             token,
+		CACertHash:        hash,
+		CertificateKey:    certKey,
+	}
+}
+
+// CreateKubeadmJoinCfg renders the JoinConfiguration of node. Masters are joined as control plane nodes.
+func CreateKubeadmJoinCfg(kubeVersion string, node model.KubeNode, joinInfo JoinInfo,
+	dc model.DeploymentConfig) []byte {
+
+	kjc := KubeadmJoinCfg{
+		ApiVersion: kubeadmApiVersion(kubeVersion),
+		Kind:       "JoinConfiguration",
+		Discovery: discovery{
+			BootstrapToken: bootstrapTokenDiscovery{
+				ApiServerEndpoint: joinInfo.ApiServerEndpoint,
+				Token:             joinInfo.Token,
+				CACertHashes:      []string{joinInfo.CACertHash},
+			},
+		},
+		NodeRegistration: createNodeRegistration(kubeVersion, node, dc),
+	}
+	kjc.NodeRegistration.Name = node.Hostname
+	if node.KubeType == "master" {
+		kjc.ControlPlane = &joinControlPlane{
+			LocalApiEndpoint: &localApiEndpoint{
+				AdvertiseAddress: node.IP.String(),
+				BindPort:         constant.ApiServerPort,
+			},
+			CertificateKey: joinInfo.CertificateKey,
+		}
+	}
+	var b bytes.Buffer
+	yamlEncoder := yamlv3.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	err := yamlEncoder.Encode(&kjc)
+	os.ThrowIfError(err, 1)
+	return b.Bytes()
+}
+
+// generateBootstrapToken returns a random token in "[a-z0-9]{6}.[a-z0-9]{16}" format.
+func generateBootstrapToken() (string, error) {
+	token := make([]byte, 22)
+	for i := range token {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(bootstrapTokenChar))))
+		if err != nil {
+			return "", err
+		}
+		token[i] = bootstrapTokenChar[n.Int64()]
+	}
+	return fmt.Sprintf("%s.%s", token[:6], token[6:]), nil
+}
+
+// caCertHash returns the hash kubeadm pins the CA with, which is the SHA-256 of the public key info of it.
+func caCertHash(caCert []byte) (string, error) {
+	block, _ := pem.Decode(caCert)
+	if block == nil {
+		return "", errors.New("CA certificate of the cluster is not in PEM format")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(hash[:])), nil
+}
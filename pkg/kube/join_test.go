@@ -0,0 +1,44 @@
+package kube
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+)
+
+func TestGenerateBootstrapToken(t *testing.T) {
+	token, err := generateBootstrapToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile("^[a-z0-9]{6}\\.[a-z0-9]{16}$").MatchString(token) {
+		t.Errorf("invalid bootstrap token \"%s\"", token)
+	}
+}
+
+func TestCreateKubeadmJoinCfg(t *testing.T) {
+	dc := testDeploymentConfig()
+	joinInfo := JoinInfo{
+		ApiServerEndpoint: "192.168.50.100:6443",
+		Token:             "abcdef.0123456789abcdef",
+		CACertHash:        "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef",
+		CertificateKey:    "certkey",
+	}
+	for kubeVersion, golden := range map[string]string{
+		"1.16.15": "join-v1beta1.golden",
+		"1.19.16": "join-v1beta2.golden",
+		"1.30.14": "join-v1beta3.golden",
+		"1.34.2":  "join-v1beta4.golden",
+	} {
+		t.Run(kubeVersion, func(t *testing.T) {
+			var b bytes.Buffer
+			for i, node := range dc.Nodes[1:] {
+				if i > 0 {
+					b.WriteString("---\n")
+				}
+				b.Write(CreateKubeadmJoinCfg(kubeVersion, node, joinInfo, dc))
+			}
+			assertGolden(t, golden, b.Bytes())
+		})
+	}
+}
@@ -60,8 +60,8 @@ func CreateCertKey(kubeVersion string, ip net.IP) (certKey string) {
 
 func UploadCerts(kubeVersion string, ip net.IP) (certKey string) {
 	certKeyOutput := os.RunCommandOn(
-		fmt.Sprintf("sudo kubeadm init phase upload-certs --upload-certs --config %s/kubeadm-config.yaml",
-			path.GetTKubeCfgDir()), ip, true)
+		fmt.Sprintf("sudo kubeadm init phase upload-certs --upload-certs --config %s",
+			path.GetKubeadmCfgFileOnMaster()), ip, true)
 	certKey = util.GetLastNonEmptyLine(certKeyOutput)
 	return certKey
 }
@@ -149,12 +149,13 @@ func isSecretExists(secretName string, namespace string) bool {
 	return output != 0
 }
 
-func SetEnv(name, namespace, env string) {
-	os.RunCommand(fmt.Sprintf("kubectl set env %s -n %s '%s'", name, namespace, env), true)
+func WaitUntilPodsRunning(namespaces []string) {
+	WaitUntilPodsRunningExcept(namespaces, nil)
 }
 
-func WaitUntilPodsRunning(namespaces []string) {
-	notReadyCount := notReadyPodCount(namespaces)
+// WaitUntilPodsRunningExcept waits pods in namespaces, except the ones whose names start with one of excluded.
+func WaitUntilPodsRunningExcept(namespaces []string, excluded []string) {
+	notReadyCount := notReadyPodCount(namespaces, excluded)
 	msg := ""
 	if len(namespaces) > 0 {
 		msg = fmt.Sprintf("Installation continues for %s pods at namespace(s): \"%s\"",
@@ -165,7 +166,7 @@ func WaitUntilPodsRunning(namespaces []string) {
 	util.StartSpinner(fmt.Sprintf(msg, notReadyCount))
 	for notReadyCount != 0 {
 		time.Sleep(util.WaitSleep)
-		notReadyCount = notReadyPodCount(namespaces)
+		notReadyCount = notReadyPodCount(namespaces, excluded)
 		util.UpdateSpinner(fmt.Sprintf(msg, notReadyCount))
 	}
 	util.StopSpinner(fmt.Sprintf("All pods running at namespace(s): %s", strings.Join(namespaces, ", ")),
@@ -195,7 +196,7 @@ func WaitUntilPodsRunningWithName(podNames []string, namespace string) {
 			runningContainer, _ := strconv.Atoi(s2[0])
 			totalContainer, _ := strconv.Atoi(s2[1])
 			if runningContainer != totalContainer {
-				notRunning = append(notRunning)
+				notRunning = append(notRunning, pod)
 			}
 		}
 		if len(notRunning) > 0 {
@@ -206,7 +207,46 @@ func WaitUntilPodsRunningWithName(podNames []string, namespace string) {
 	util.StopSpinner(fmt.Sprintf("%s pods running", strings.Join(podNames, ", ")), logsymbols.Success)
 }
 
-func notReadyPodCount(namespaces []string) int {
+// apiServerStatus returns the start time of the apiserver container of the master and whether it is ready, empty if
+// the pod is not found or the apiserver can not be reached.
+func apiServerStatus(hostname string) (startedAt string, ready bool) {
+	out, err := os.RunCommandReturnError(fmt.Sprintf("kubectl get pod --ignore-not-found -n kube-system "+
+		"kube-apiserver-%s -o jsonpath='{.status.containerStatuses[0].state.running.startedAt} "+
+		"{.status.containerStatuses[0].ready}'", hostname), true)
+	fields := strings.Fields(out)
+	if err != nil || len(fields) != 2 {
+		return "", false
+	}
+	return fields[0], fields[1] == "true"
+}
+
+// ApiServerStartedAt returns the start time of the running apiserver container of the master.
+func ApiServerStartedAt(hostname string) string {
+	startedAt, _ := apiServerStatus(hostname)
+	return startedAt
+}
+
+// WaitUntilApiServerStopped waits until kubelet removes the apiserver pod of the master.
+func WaitUntilApiServerStopped(hostname string) {
+	for startedAt, _ := apiServerStatus(hostname); startedAt != ""; startedAt, _ = apiServerStatus(hostname) {
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// WaitUntilApiServerRestarted waits until the apiserver of the master is ready in a container started after the one
+// started at startedAt. The pod reports the old container for a while after its manifest changes, so being ready is
+// not enough.
+func WaitUntilApiServerRestarted(hostname string, startedAt string) {
+	for {
+		current, ready := apiServerStatus(hostname)
+		if current != "" && current != startedAt && ready {
+			return
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func notReadyPodCount(namespaces []string, excluded []string) int {
 	namespacesCmd := ""
 	if len(namespaces) > 0 {
 		namespacesCmd = fmt.Sprintf(" | grep -E '%s'", strings.Join(namespaces, "|"))
@@ -220,7 +260,9 @@ func notReadyPodCount(namespaces []string) int {
 	}
 	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
 		s1 := strings.Fields(line)
-		if len(s1) < 3 {
+		if len(s1) < 3 || slices.ContainsFunc(excluded, func(prefix string) bool {
+			return strings.HasPrefix(s1[1], prefix)
+		}) {
 			continue
 		}
 		ready := s1[2]
@@ -18,26 +18,71 @@ import (
 const (
 	kubeAdminConfPath = "/etc/kubernetes/admin.conf"
 	kubeletConfPath   = "/etc/kubernetes/kubelet.conf"
+	kubeadmV1Beta1    = "kubeadm.k8s.io/v1beta1"
+	kubeadmV1Beta2    = "kubeadm.k8s.io/v1beta2"
+	kubeadmV1Beta3    = "kubeadm.k8s.io/v1beta3"
+	kubeadmV1Beta4    = "kubeadm.k8s.io/v1beta4"
 )
 
 type KubeadmClusterCfg struct {
-	ApiVersion           string      `yaml:"apiVersion" json:"apiVersion"`
-	Kind                 string      `yaml:"kind" json:"kind"`
-	KubernetesVersion    string      `yaml:"kubernetesVersion" json:"kubernetesVersion"`
-	ApiServer            *apiServer  `yaml:"apiServer" json:"apiServer"`
-	ControlPlaneEndpoint string      `yaml:"controlPlaneEndpoint" json:"controlPlaneEndpoint"`
-	Networking           *networking `yaml:"networking" json:"networking"`
-	Etcd                 *etcd       `yaml:"etcd" json:"etcd,omitempty"`
-	ImageRepository      string      `yaml:"imageRepository" json:"imageRepository"`
-	Dns                  *dns        `yaml:"dns" json:"dns,omitempty"`
+	ApiVersion           string                 `yaml:"apiVersion" json:"apiVersion"`
+	Kind                 string                 `yaml:"kind" json:"kind"`
+	KubernetesVersion    string                 `yaml:"kubernetesVersion" json:"kubernetesVersion"`
+	ClusterName          string                 `yaml:"clusterName,omitempty" json:"clusterName,omitempty"`
+	ApiServer            *apiServer             `yaml:"apiServer" json:"apiServer"`
+	ControllerManager    *controlPlaneComponent `yaml:"controllerManager,omitempty" json:"controllerManager,omitempty"`
+	Scheduler            *controlPlaneComponent `yaml:"scheduler,omitempty" json:"scheduler,omitempty"`
+	ControlPlaneEndpoint string                 `yaml:"controlPlaneEndpoint" json:"controlPlaneEndpoint"`
+	Networking           *networking            `yaml:"networking" json:"networking"`
+	Etcd                 *etcd                  `yaml:"etcd" json:"etcd,omitempty"`
+	ImageRepository      string                 `yaml:"imageRepository" json:"imageRepository"`
+	Dns                  *dns                   `yaml:"dns" json:"dns,omitempty"`
+}
+
+type controlPlaneComponent struct {
+	ExtraArgs    extraArgs             `yaml:"extraArgs,omitempty" json:"extraArgs,omitempty"`
+	ExtraVolumes []model.HostPathMount `yaml:"extraVolumes,omitempty" json:"extraVolumes,omitempty"`
+}
+
+// extraArgs are written as a map up to v1beta3, v1beta4 turned them into a list of name/value pairs.
+type extraArgs struct {
+	args   map[string]string
+	asList bool
+}
+
+type extraArg struct {
+	Name  string `yaml:"name" json:"name"`
+	Value string `yaml:"value" json:"value"`
+}
+
+func newExtraArgs(apiVersion string, args map[string]string) extraArgs {
+	return extraArgs{args: args, asList: apiVersion == kubeadmV1Beta4}
+}
+
+func (a extraArgs) IsZero() bool {
+	return len(a.args) == 0
+}
+
+func (a extraArgs) MarshalYAML() (interface{}, error) {
+	if !a.asList {
+		return a.args, nil
+	}
+	list := []extraArg{}
+	for _, k := range model.SortedKeys(a.args) {
+		list = append(list, extraArg{Name: k, Value: a.args[k]})
+	}
+	return list, nil
 }
 
 type apiServer struct {
-	CertSANs []string `yaml:"certSANs" json:"certSANs"`
+	controlPlaneComponent `yaml:",inline"`
+	CertSANs              []string `yaml:"certSANs" json:"certSANs"`
 }
 
 type networking struct {
-	PodSubnet string `yaml:"podSubnet" json:"podSubnet"`
+	PodSubnet     string `yaml:"podSubnet" json:"podSubnet"`
+	ServiceSubnet string `yaml:"serviceSubnet,omitempty" json:"serviceSubnet,omitempty"`
+	DNSDomain     string `yaml:"dnsDomain,omitempty" json:"dnsDomain,omitempty"`
 }
 
 type etcd struct {
@@ -60,6 +105,7 @@ type KubeadmInitCfg struct {
 	Kind             string            `yaml:"kind" json:"kind"`
 	CertificateKey   string            `yaml:"certificateKey" json:"certificateKey"`
 	LocalApiEndpoint *localApiEndpoint `yaml:"localAPIEndpoint" json:"localAPIEndpoint"`
+	NodeRegistration *nodeRegistration `yaml:"nodeRegistration,omitempty" json:"nodeRegistration,omitempty"`
 }
 
 type localApiEndpoint struct {
@@ -68,11 +114,17 @@ type localApiEndpoint struct {
 }
 
 type KubeletCfg struct {
-	ApiVersion           string `yaml:"apiVersion" json:"apiVersion"`
-	Kind                 string `yaml:"kind" json:"kind"`
-	CGroupDriver         string `yaml:"cgroupDriver" json:"cgroupDriver"`
-	ContainerLogMaxSize  string `yaml:"containerLogMaxSize" json:"containerLogMaxSize,omitempty"`
-	ContainerLogMaxFiles int    `yaml:"containerLogMaxFiles" json:"containerLogMaxFiles,omitempty"`
+	ApiVersion    string `yaml:"apiVersion" json:"apiVersion"`
+	Kind          string `yaml:"kind" json:"kind"`
+	CGroupDriver  string `yaml:"cgroupDriver" json:"cgroupDriver"`
+	model.Kubelet `yaml:",inline"`
+}
+
+type KubeProxyCfg struct {
+	ApiVersion string               `yaml:"apiVersion" json:"apiVersion"`
+	Kind       string               `yaml:"kind" json:"kind"`
+	Mode       string               `yaml:"mode,omitempty" json:"mode,omitempty"`
+	IPVS       *model.KubeProxyIPVS `yaml:"ipvs,omitempty" json:"ipvs,omitempty"`
 }
 
 type KubeConfig struct {
@@ -100,57 +152,76 @@ type ContextMapItem struct {
 	Value kubeApi.Context `json:"context,omitempty"`
 }
 
-func CreateCombinedKubeadmCfg(kubeVersion string, controlPlaneIP net.IP, advertiseIP net.IP, certKey string,
+func CreateCombinedKubeadmCfg(kubeVersion string, controlPlaneEndpoint string, firstMaster model.KubeNode, certKey string,
 	dc model.DeploymentConfig, multiMasterDeployment bool) []byte {
 
 	var b bytes.Buffer
 	yamlEncoder := yamlv3.NewEncoder(&b)
 	yamlEncoder.SetIndent(2)
-	kubeadmInitCfg := createDefaultKubeadmInitCfg(kubeVersion, advertiseIP, certKey)
+	kubeadmInitCfg := createDefaultKubeadmInitCfg(kubeVersion, firstMaster.IP, certKey)
+	kubeadmInitCfg.NodeRegistration = createNodeRegistration(kubeVersion, firstMaster, dc)
 	yamlEncoder.Encode(&kubeadmInitCfg)
 	b.WriteString("\n")
-	kubeadmClusterCfg := createKubeadmClusterCfg(kubeVersion, controlPlaneIP, dc, multiMasterDeployment)
+	kubeadmClusterCfg := createKubeadmClusterCfg(kubeVersion, controlPlaneEndpoint, dc, multiMasterDeployment)
 	yamlEncoder.Encode(&kubeadmClusterCfg)
 	b.WriteString("\n")
+	if dc.Kubernetes.KubeProxy.Mode != "" && !dc.Kubernetes.KubeProxy.Skip {
+		kubeProxyCfg := createKubeProxyCfg(dc)
+		yamlEncoder.Encode(&kubeProxyCfg)
+		b.WriteString("\n")
+	}
 	kubeletCfg := createKubeadmKubeletCfg(kubeVersion, dc)
 	yamlEncoder.Encode(&kubeletCfg)
 	b.WriteString("\n")
 	return b.Bytes()
 }
 
-func createKubeadmClusterCfg(kubeVersion string, controlPlaneIP net.IP,
+func createKubeadmClusterCfg(kubeVersion string, controlPlaneEndpoint string,
 	dc model.DeploymentConfig, multiMasterDeployment bool) (kcc KubeadmClusterCfg) {
 
 	kubeSemVer, _ := version.NewVersion(kubeVersion)
-	kubeadmCfgApiSemVerV3, _ := version.NewVersion("1.20.0")
-	kubeadmCfgApiSemVerV2, _ := version.NewVersion("1.17.0")
-	if kubeSemVer.GreaterThanOrEqual(kubeadmCfgApiSemVerV3) {
-		kcc.ApiVersion = "kubeadm.k8s.io/v1beta3"
-	} else if kubeSemVer.GreaterThanOrEqual(kubeadmCfgApiSemVerV2) {
-		kcc.ApiVersion = "kubeadm.k8s.io/v1beta2"
-	} else {
-		kcc.ApiVersion = "kubeadm.k8s.io/v1beta1"
-	}
+	kcc.ApiVersion = kubeadmApiVersion(kubeVersion)
 	kcc.Kind = "ClusterConfiguration"
 	kcc.KubernetesVersion = fmt.Sprintf("v%s", kubeSemVer.String())
 	var apiServerCertSANs []string
-	for _, ip := range dc.GetMasterKubeNodeIPs() {
-		apiServerCertSANs = append(apiServerCertSANs, ip.String())
+	for _, master := range dc.GetMasterKubeNodes() {
+		for _, ip := range append([]net.IP{master.IP}, master.IPv6) {
+			if ip != nil {
+				apiServerCertSANs = append(apiServerCertSANs, ip.String())
+			}
+		}
 	}
-	if dc.Keepalived.Enabled {
-		apiServerCertSANs = append(apiServerCertSANs, dc.Keepalived.VirtualIP.String())
+	if dc.VIPEnabled() {
+		apiServerCertSANs = append(apiServerCertSANs, dc.GetVirtualIP().String())
 	}
+	if dc.ExternalEndpointEnabled() {
+		apiServerCertSANs = append(apiServerCertSANs, dc.GetControlPlaneEndpointHost())
+	}
+	apiServerCertSANs = append(apiServerCertSANs, dc.Kubernetes.CertSANs...)
+	kcc.ClusterName = dc.Kubernetes.ClusterName
 	kcc.ApiServer = &apiServer{
-		CertSANs: apiServerCertSANs,
+		controlPlaneComponent: newControlPlaneComponent(kcc.ApiVersion, dc.GetApiServer()),
+		CertSANs:              apiServerCertSANs,
+	}
+	if c := newControlPlaneComponent(kcc.ApiVersion, dc.Kubernetes.ControllerManager); !c.ExtraArgs.IsZero() ||
+		c.ExtraVolumes != nil {
+		kcc.ControllerManager = &c
+	}
+	if c := newControlPlaneComponent(kcc.ApiVersion, dc.Kubernetes.Scheduler); !c.ExtraArgs.IsZero() ||
+		c.ExtraVolumes != nil {
+		kcc.Scheduler = &c
 	}
-	kcc.ControlPlaneEndpoint = fmt.Sprintf("%s:6443", controlPlaneIP)
+	kcc.ControlPlaneEndpoint = controlPlaneEndpoint
 	kcc.Networking = &networking{
-		PodSubnet: dc.Kubernetes.PodSubnet,
+		PodSubnet:     dc.Kubernetes.PodSubnet,
+		ServiceSubnet: dc.Kubernetes.ServiceSubnet,
+		DNSDomain:     dc.Kubernetes.DNSDomain,
 	}
 	if multiMasterDeployment {
 		var etcdExternalEndpoints []string
 		for _, ip := range dc.GetMasterKubeNodeIPs() {
-			etcdExternalEndpoints = append(etcdExternalEndpoints, fmt.Sprintf("https://%s:2379", ip))
+			etcdExternalEndpoints = append(etcdExternalEndpoints, fmt.Sprintf("https://%s",
+				net.JoinHostPort(ip.String(), "2379")))
 		}
 		kcc.Etcd = &etcd{
 			External: &etcdExternal{
@@ -168,32 +239,76 @@ func createKubeadmClusterCfg(kubeVersion string, controlPlaneIP net.IP,
 	return kcc
 }
 
-func createDefaultKubeadmInitCfg(kubeVersion string, advertiseIP net.IP,
-	certKey string) (kic KubeadmInitCfg) {
-
+// kubeadmApiVersion returns the newest kubeadm config API kubeadm of kubeVersion supports.
+func kubeadmApiVersion(kubeVersion string) string {
 	kubeSemVer, _ := version.NewVersion(kubeVersion)
+	kubeadmCfgApiSemVerV4, _ := version.NewVersion("1.31.0")
 	kubeadmCfgApiSemVerV3, _ := version.NewVersion("1.20.0")
 	kubeadmCfgApiSemVerV2, _ := version.NewVersion("1.17.0")
-	if kubeSemVer.GreaterThanOrEqual(kubeadmCfgApiSemVerV3) {
-		kic.ApiVersion = "kubeadm.k8s.io/v1beta3"
+	if kubeSemVer.GreaterThanOrEqual(kubeadmCfgApiSemVerV4) {
+		return kubeadmV1Beta4
+	} else if kubeSemVer.GreaterThanOrEqual(kubeadmCfgApiSemVerV3) {
+		return kubeadmV1Beta3
 	} else if kubeSemVer.GreaterThanOrEqual(kubeadmCfgApiSemVerV2) {
-		kic.ApiVersion = "kubeadm.k8s.io/v1beta2"
-	} else {
-		kic.ApiVersion = "kubeadm.k8s.io/v1beta1"
+		return kubeadmV1Beta2
+	}
+	return kubeadmV1Beta1
+}
+
+func newControlPlaneComponent(apiVersion string, component model.ControlPlaneComponent) controlPlaneComponent {
+	return controlPlaneComponent{
+		ExtraArgs:    newExtraArgs(apiVersion, component.GetExtraArgs()),
+		ExtraVolumes: component.ExtraVolumes,
 	}
+}
+
+func createDefaultKubeadmInitCfg(kubeVersion string, advertiseIP net.IP,
+	certKey string) (kic KubeadmInitCfg) {
+
+	kic.ApiVersion = kubeadmApiVersion(kubeVersion)
 	kic.Kind = "InitConfiguration"
 	kic.CertificateKey = certKey
 	if advertiseIP != nil {
 		kic.LocalApiEndpoint = &localApiEndpoint{
 			AdvertiseAddress: advertiseIP.String(),
-			BindPort:         6443,
+			BindPort:         constant.ApiServerPort,
 		}
-		kic.LocalApiEndpoint.AdvertiseAddress = advertiseIP.String()
-		kic.LocalApiEndpoint.BindPort = 6443
 	}
 	return kic
 }
 
+func createNodeRegistration(kubeVersion string, node model.KubeNode, dc model.DeploymentConfig) *nodeRegistration {
+	settings := dc.GetNodeSettings(node)
+	// an empty list means no taints for kubeadm, so the default control plane taint is added explicitly
+	taints := []model.Taint{}
+	if node.KubeType == "master" && !dc.Kubernetes.SchedulePodsOnMasters {
+		taints = append(taints, ControlPlaneTaint(kubeVersion))
+	}
+	taints = append(taints, settings.Taints...)
+	return &nodeRegistration{
+		Taints:           taints,
+		KubeletExtraArgs: newExtraArgs(kubeadmApiVersion(kubeVersion), KubeletExtraArgs(node, dc)),
+	}
+}
+
+func createKubeProxyCfg(dc model.DeploymentConfig) (kpc KubeProxyCfg) {
+	kpc.ApiVersion = "kubeproxy.config.k8s.io/v1alpha1"
+	kpc.Kind = "KubeProxyConfiguration"
+	kpc.Mode = dc.Kubernetes.KubeProxy.Mode
+	if ipvs := dc.Kubernetes.KubeProxy.IPVS; ipvs != (model.KubeProxyIPVS{}) {
+		kpc.IPVS = &ipvs
+	}
+	return kpc
+}
+
+// KubeProxySkipFlag returns the kubeadm init flag leaving kube-proxy out if it is skipped.
+func KubeProxySkipFlag(dc model.DeploymentConfig) string {
+	if dc.Kubernetes.KubeProxy.Skip {
+		return " --skip-phases=addon/kube-proxy"
+	}
+	return ""
+}
+
 func createKubeadmKubeletCfg(kubeVersion string, dc model.DeploymentConfig) (kc KubeletCfg) {
 	kc.ApiVersion = "kubelet.config.k8s.io/v1beta1"
 	kc.Kind = "KubeletConfiguration"
@@ -205,28 +320,37 @@ func createKubeadmKubeletCfg(kubeVersion string, dc model.DeploymentConfig) (kc
 	if kc.CGroupDriver == "" {
 		kc.CGroupDriver = "systemd"
 	}
+	if dc.Kubernetes.Kubelet != nil {
+		kc.Kubelet = *dc.Kubernetes.Kubelet
+	}
 	kubeSemVer, _ := version.NewVersion(kubeVersion)
 	kube124Ver, _ := version.NewVersion("1.24")
 	if kubeSemVer.GreaterThanOrEqual(kube124Ver) {
-		kc.ContainerLogMaxFiles, _ = strconv.Atoi(dc.Docker.Daemon.LogOpts.MaxFile)
-		kc.ContainerLogMaxSize = strings.ReplaceAll(dc.Docker.Daemon.LogOpts.MaxSize, "m", "Mi")
+		// log rotation of kubelet replaces the one of docker daemon
+		if kc.ContainerLogMaxFiles == 0 {
+			kc.ContainerLogMaxFiles, _ = strconv.Atoi(dc.Docker.Daemon.LogOpts.MaxFile)
+		}
+		if kc.ContainerLogMaxSize == "" {
+			kc.ContainerLogMaxSize = strings.ReplaceAll(dc.Docker.Daemon.LogOpts.MaxSize, "m", "Mi")
+		}
 	}
 	return kc
 }
 
-func UpdateServerInfoOnKubeAdminConf(ip net.IP) {
+// UpdateServerInfoOnKubeAdminConf points admin.conf of the node to server, which is https://host:port.
+func UpdateServerInfoOnKubeAdminConf(ip net.IP, server string) {
 	os.RunCommandOn(fmt.Sprintf("sudo chmod -R 777 %s", "/etc/kubernetes"), ip, true)
-	updateServerInfoOnKubeConfig(kubeAdminConfPath, ip)
+	updateServerInfoOnKubeConfig(kubeAdminConfPath, ip, server)
 	os.RunCommandOn(fmt.Sprintf("sudo chmod -R 644 %s", "/etc/kubernetes"), ip, true)
 }
 
-func UpdateServerInfoOnKubeletConf(ip net.IP) {
+func UpdateServerInfoOnKubeletConf(ip net.IP, server string) {
 	os.RunCommandOn(fmt.Sprintf("sudo chmod -R 777 %s", "/etc/kubernetes"), ip, true)
-	updateServerInfoOnKubeConfig(kubeletConfPath, ip)
+	updateServerInfoOnKubeConfig(kubeletConfPath, ip, server)
 	os.RunCommandOn(fmt.Sprintf("sudo chmod -R 644 %s", "/etc/kubernetes"), ip, true)
 }
 
-func updateServerInfoOnKubeConfig(filepath string, ip net.IP) {
+func updateServerInfoOnKubeConfig(filepath string, ip net.IP, server string) {
 	data, err := os.ReadFile(filepath, ip)
 	if err != nil {
 		os.Exit(err.Error(), 1)
@@ -237,7 +361,7 @@ func updateServerInfoOnKubeConfig(filepath string, ip net.IP) {
 	if err != nil {
 		os.Exit(err.Error(), 1)
 	}
-	kubeAdminConf.Clusters[0].Value.Server = fmt.Sprintf("https://%s:6443", ip)
+	kubeAdminConf.Clusters[0].Value.Server = server
 	var kubeAdminConfData []byte
 	kubeAdminConfData, err = yaml.Marshal(&kubeAdminConf)
 	if err != nil {
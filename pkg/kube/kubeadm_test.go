@@ -0,0 +1,153 @@
+package kube
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	goos "os"
+	"path/filepath"
+	"testing"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/util"
+)
+
+var update = flag.Bool("update", false, "update golden files of kubeadm configs")
+
+func TestKubeadmApiVersion(t *testing.T) {
+	for kubeVersion, expected := range map[string]string{
+		"1.16.15": kubeadmV1Beta1,
+		"1.17.0":  kubeadmV1Beta2,
+		"1.19.16": kubeadmV1Beta2,
+		"1.20.0":  kubeadmV1Beta3,
+		"1.30.14": kubeadmV1Beta3,
+		"1.31.0":  kubeadmV1Beta4,
+		"1.34.2":  kubeadmV1Beta4,
+	} {
+		if actual := kubeadmApiVersion(kubeVersion); actual != expected {
+			t.Errorf("kubeadm config API of %s is %s, expected %s", kubeVersion, actual, expected)
+		}
+	}
+}
+
+func TestCreateCombinedKubeadmCfg(t *testing.T) {
+	dc := testDeploymentConfig()
+	for kubeVersion, golden := range map[string]string{
+		"1.16.15": "kubeadm-v1beta1.golden",
+		"1.19.16": "kubeadm-v1beta2.golden",
+		"1.30.14": "kubeadm-v1beta3.golden",
+		"1.34.2":  "kubeadm-v1beta4.golden",
+	} {
+		t.Run(kubeVersion, func(t *testing.T) {
+			actual := CreateCombinedKubeadmCfg(kubeVersion, "192.168.50.100:6443", dc.Nodes[0], "certkey", dc, true)
+			assertGolden(t, golden, actual)
+		})
+	}
+}
+
+func TestCreateCombinedKubeadmCfgDualStack(t *testing.T) {
+	dc := testDeploymentConfig()
+	dc.Kubernetes.PodSubnet = "10.244.0.0/16,fd00:10:244::/56"
+	dc.Kubernetes.ServiceSubnet = "10.96.0.0/12,fd00:10:96::/112"
+	for i := range dc.Nodes {
+		dc.Nodes[i].IPv6 = net.ParseIP(fmt.Sprintf("fd00:50::%d", i+10))
+	}
+	if err := dc.ValidateKubeadm(); err != nil {
+		t.Fatal(err)
+	}
+	actual := CreateCombinedKubeadmCfg("1.34.2", "192.168.50.100:6443", dc.Nodes[0], "certkey", dc, true)
+	assertGolden(t, "kubeadm-dual-stack.golden", actual)
+}
+
+func TestCreateCombinedKubeadmCfgKubeProxy(t *testing.T) {
+	dc := testDeploymentConfig()
+	dc.Kubernetes.KubeProxy = model.KubeProxy{
+		Mode: model.KubeProxyModeIPVS,
+		IPVS: model.KubeProxyIPVS{Scheduler: "lc", StrictARP: true},
+	}
+	if err := dc.ValidateKubeProxy(); err != nil {
+		t.Fatal(err)
+	}
+	actual := CreateCombinedKubeadmCfg("1.34.2", "192.168.50.100:6443", dc.Nodes[0], "certkey", dc, true)
+	assertGolden(t, "kubeadm-kube-proxy.golden", actual)
+}
+
+func TestCreateCombinedKubeadmCfgAudit(t *testing.T) {
+	dc := testDeploymentConfig()
+	dc.Kubernetes.ApiServer = model.ControlPlaneComponent{}
+	dc.Kubernetes.Audit = model.Audit{
+		Enabled: true,
+		MaxAge:  90,
+		Webhook: &model.AuditWebhook{KubeConfig: "apiVersion: v1", Mode: "batch"},
+	}
+	if err := dc.ValidateAudit(); err != nil {
+		t.Fatal(err)
+	}
+	actual := CreateCombinedKubeadmCfg("1.30.14", "192.168.50.100:6443", dc.Nodes[0], "certkey", dc, true)
+	assertGolden(t, "kubeadm-audit.golden", actual)
+}
+
+// assertGolden compares actual with the golden file in testdata, the file is written instead with -update.
+func assertGolden(t *testing.T, golden string, actual []byte) {
+	t.Helper()
+	goldenFile := filepath.Join("testdata", golden)
+	if *update {
+		if err := goos.WriteFile(goldenFile, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expected, err := goos.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(actual) != string(expected) {
+		t.Errorf("output differs from %s, run with -update if it is intended:\n%s", goldenFile,
+			util.Diff(expected, actual))
+	}
+}
+
+func testDeploymentConfig() model.DeploymentConfig {
+	return model.DeploymentConfig{
+		Nodes: []model.KubeNode{
+			{
+				Hostname: "master1",
+				IP:       net.ParseIP("192.168.50.10"),
+				KubeType: "master",
+				NodeSettings: model.NodeSettings{
+					Labels:           map[string]string{"tier": "control", "node-role.kubernetes.io/infra": ""},
+					Taints:           []model.Taint{{Key: "dedicated", Value: "infra", Effect: "NoSchedule"}},
+					KubeletExtraArgs: map[string]string{"v": "2"},
+				},
+			},
+			{Hostname: "master2", IP: net.ParseIP("192.168.50.11"), KubeType: "master"},
+			{
+				Hostname:     "worker1",
+				IP:           net.ParseIP("192.168.50.20"),
+				KubeType:     "worker",
+				NodeSettings: model.NodeSettings{Labels: map[string]string{"tier": "app"}},
+			},
+		},
+		Docker: model.Docker{
+			Daemon: model.DockerDaemonCfg{
+				ExecOpts: []string{"native.cgroupdriver=systemd"},
+				LogOpts:  model.DockerDaemonLogOpts{MaxFile: "3", MaxSize: "100m"},
+			},
+		},
+		Kubernetes: model.Kubernetes{
+			ImageRegistry: "registry.example.com",
+			PodSubnet:     "10.244.0.0/16",
+			ServiceSubnet: "10.96.0.0/12",
+			DNSDomain:     "cluster.local",
+			ClusterName:   "lab",
+			CertSANs:      []string{"kube.example.com"},
+			ApiServer: model.ControlPlaneComponent{
+				ExtraArgs:    map[string]string{"audit-log-maxage": "30", "enable-admission-plugins": "NodeRestriction"},
+				ExtraVolumes: []model.HostPathMount{{Name: "audit", HostPath: "/var/log/audit", MountPath: "/var/log/audit"}},
+			},
+			ControllerManager: model.ControlPlaneComponent{
+				FeatureGates: map[string]bool{"RotateKubeletServerCertificate": true},
+			},
+			Kubelet: &model.Kubelet{MaxPods: 150},
+		},
+	}
+}
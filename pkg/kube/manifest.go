@@ -0,0 +1,241 @@
+package kube
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var invalidNameCharRegex = regexp.MustCompile(`[^a-z0-9.-]`)
+
+// manifest is a multi-document yaml of kubernetes resources, edited in place to keep comments and order of the
+// original file.
+type manifest []*yamlv3.Node
+
+func parseManifest(data []byte) (manifest, error) {
+	var m manifest
+	decoder := yamlv3.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yamlv3.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return m, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(doc.Content) > 0 && doc.Content[0].Kind == yamlv3.MappingNode {
+			m = append(m, doc.Content[0])
+		}
+	}
+}
+
+func (m manifest) Marshal() ([]byte, error) {
+	var b bytes.Buffer
+	yamlEncoder := yamlv3.NewEncoder(&b)
+	yamlEncoder.SetIndent(2)
+	for _, doc := range m {
+		if err := yamlEncoder.Encode(doc); err != nil {
+			return nil, err
+		}
+	}
+	err := yamlEncoder.Close()
+	return b.Bytes(), err
+}
+
+// resources returns the documents of kind, all of them if name is empty.
+func (m manifest) resources(kind, name string) []*yamlv3.Node {
+	var resources []*yamlv3.Node
+	for _, doc := range m {
+		if nodeValue(doc, "kind").Value != kind {
+			continue
+		}
+		if name == "" || nodeValue(doc, "metadata", "name").Value == name {
+			resources = append(resources, doc)
+		}
+	}
+	return resources
+}
+
+// containers returns containers and init containers of a workload resource.
+func containers(resource *yamlv3.Node) []*yamlv3.Node {
+	spec := nodeValue(resource, "spec", "template", "spec")
+	return append(append([]*yamlv3.Node{}, nodeValue(spec, "initContainers").Content...),
+		nodeValue(spec, "containers").Content...)
+}
+
+func findContainer(resource *yamlv3.Node, name string) *yamlv3.Node {
+	for _, container := range containers(resource) {
+		if nodeValue(container, "name").Value == name {
+			return container
+		}
+	}
+	return nil
+}
+
+// setContainerEnv sets the value of the env var, replacing a valueFrom of it.
+func setContainerEnv(container *yamlv3.Node, name, value string) {
+	env := nodeValue(container, "env")
+	if env.Kind != yamlv3.SequenceNode {
+		*env = yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+		container.Content = append(container.Content, scalarNode("env"), env)
+	}
+	valueNode := scalarNode(value)
+	valueNode.Style = yamlv3.DoubleQuotedStyle
+	content := []*yamlv3.Node{scalarNode("name"), scalarNode(name), scalarNode("value"), valueNode}
+	for _, e := range env.Content {
+		if nodeValue(e, "name").Value == name {
+			e.Content = content
+			return
+		}
+	}
+	env.Content = append(env.Content, &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map", Content: content})
+}
+
+// rewriteImages replaces registry from of the images in workloads with registry to, every registry is replaced if
+// from is empty. Images without a registry are pulled from docker.io.
+func (m manifest) rewriteImages(from, to string) {
+	for _, doc := range m {
+		for _, container := range containers(doc) {
+			image := nodeValue(container, "image")
+			image.Value = replaceImageRegistry(image.Value, from, to)
+		}
+	}
+}
+
+func replaceImageRegistry(image, from, to string) string {
+	registry, repository, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(registry, ".:") && registry != "localhost") {
+		registry, repository = "docker.io", image
+	}
+	if from != "" && registry != from {
+		return image
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(to, "/"), repository)
+}
+
+// pinNodeInterfaces makes daemonSet use the interface of each node with pin. If all nodes share an interface
+// daemonSet is pinned to it. Otherwise a copy of daemonSet is added for each interface, running on the nodes labeled
+// with it, and daemonSet keeps running on nodes without an interface.
+func (m *manifest) pinNodeInterfaces(daemonSet *yamlv3.Node, interfaces []string, shared bool,
+	pin func(daemonSet *yamlv3.Node, iface string)) {
+
+	if shared {
+		pin(daemonSet, interfaces[0])
+		return
+	}
+	if len(interfaces) == 0 {
+		return
+	}
+	var copies []*yamlv3.Node
+	name := nodeValue(daemonSet, "metadata", "name").Value
+	for _, iface := range interfaces {
+		c := copyNode(daemonSet)
+		nodeValue(c, "metadata", "name").Value = fmt.Sprintf("%s-%s", name,
+			invalidNameCharRegex.ReplaceAllString(strings.ToLower(iface), "-"))
+		for _, labels := range [][]string{{"spec", "selector", "matchLabels"}, {"spec", "template", "metadata", "labels"},
+			{"spec", "template", "spec", "nodeSelector"}} {
+			setMappingValue(mappingValue(c, labels...), model.NetworkInterfaceLabel, iface)
+		}
+		pin(c, iface)
+		copies = append(copies, c)
+	}
+	// nodes without the label are selected in each term of the affinity, terms are ORed
+	terms := mappingValue(daemonSet, "spec", "template", "spec", "affinity", "nodeAffinity",
+		"requiredDuringSchedulingIgnoredDuringExecution")
+	selectorTerms := nodeValue(terms, "nodeSelectorTerms")
+	if selectorTerms.Kind != yamlv3.SequenceNode {
+		selectorTerms = &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+		terms.Content = append(terms.Content, scalarNode("nodeSelectorTerms"), selectorTerms)
+	}
+	if len(selectorTerms.Content) == 0 {
+		selectorTerms.Content = append(selectorTerms.Content, &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"})
+	}
+	for _, term := range selectorTerms.Content {
+		expressions := nodeValue(term, "matchExpressions")
+		if expressions.Kind != yamlv3.SequenceNode {
+			expressions = &yamlv3.Node{Kind: yamlv3.SequenceNode, Tag: "!!seq"}
+			term.Content = append(term.Content, scalarNode("matchExpressions"), expressions)
+		}
+		expressions.Content = append(expressions.Content, &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map",
+			Content: []*yamlv3.Node{scalarNode("key"), scalarNode(model.NetworkInterfaceLabel),
+				scalarNode("operator"), scalarNode("DoesNotExist")}})
+	}
+	i := slices.Index(*m, daemonSet)
+	*m = slices.Insert(*m, i+1, copies...)
+}
+
+func copyNode(node *yamlv3.Node) *yamlv3.Node {
+	c := *node
+	c.Content = make([]*yamlv3.Node, len(node.Content))
+	for i, child := range node.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
+
+// mappingValue follows keys through mappings like nodeValue, missing mappings are created.
+func mappingValue(node *yamlv3.Node, keys ...string) *yamlv3.Node {
+	for _, key := range keys {
+		value := nodeValue(node, key)
+		if value.Kind != yamlv3.MappingNode {
+			value = &yamlv3.Node{Kind: yamlv3.MappingNode, Tag: "!!map"}
+			setMappingNode(node, key, value)
+		}
+		node = value
+	}
+	return node
+}
+
+func setMappingValue(node *yamlv3.Node, key, value string) {
+	setMappingNode(node, key, scalarNode(value))
+}
+
+func setMappingNode(node *yamlv3.Node, key string, value *yamlv3.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content, scalarNode(key), value)
+}
+
+func removeScalars(nodes []*yamlv3.Node, values ...string) []*yamlv3.Node {
+	var kept []*yamlv3.Node
+	for _, node := range nodes {
+		if !slices.Contains(values, node.Value) {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
+
+func scalarNode(value string) *yamlv3.Node {
+	return &yamlv3.Node{Kind: yamlv3.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// nodeValue follows keys through mappings, an empty node is returned if a key is missing.
+func nodeValue(node *yamlv3.Node, keys ...string) *yamlv3.Node {
+	for _, key := range keys {
+		var value *yamlv3.Node
+		for i := 0; node.Kind == yamlv3.MappingNode && i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				value = node.Content[i+1]
+				break
+			}
+		}
+		if value == nil {
+			return &yamlv3.Node{}
+		}
+		node = value
+	}
+	return node
+}
@@ -0,0 +1,131 @@
+package kube
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"github.com/hashicorp/go-version"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	managedLabelsAnnotation = "tkube.io/managed-labels"
+	managedTaintsAnnotation = "tkube.io/managed-taints"
+)
+
+type nodeRegistration struct {
+	Name             string        `yaml:"name,omitempty" json:"name,omitempty"`
+	Taints           []model.Taint `yaml:"taints" json:"taints"`
+	KubeletExtraArgs extraArgs     `yaml:"kubeletExtraArgs,omitempty" json:"kubeletExtraArgs,omitempty"`
+}
+
+// ControlPlaneTaint returns the taint kubeadm puts on control plane nodes by default.
+func ControlPlaneTaint(kubeVersion string) model.Taint {
+	kubeSemVer, _ := version.NewVersion(kubeVersion)
+	kube124Ver, _ := version.NewVersion("1.24")
+	if kubeSemVer.LessThan(kube124Ver) {
+		return model.Taint{Key: "node-role.kubernetes.io/master", Effect: "NoSchedule"}
+	}
+	return model.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: "NoSchedule"}
+}
+
+// KubeletExtraArgs returns kubelet flags for node. Labels which kubelet is not allowed to set on its own node are
+// left out, they are applied later by ApplyNodeSettings.
+func KubeletExtraArgs(node model.KubeNode, dc model.DeploymentConfig) map[string]string {
+	settings := dc.GetNodeSettings(node)
+	args := make(map[string]string)
+	for k, v := range settings.KubeletExtraArgs {
+		args[k] = v
+	}
+	// kubelet reports only the address of the default route unless both addresses are given in dual-stack
+	if node.NodeIP != nil || dc.DualStack() {
+		var nodeIPs []string
+		for _, ip := range dc.GetNodeIPs(node) {
+			nodeIPs = append(nodeIPs, ip.String())
+		}
+		args["node-ip"] = strings.Join(nodeIPs, ",")
+	}
+	var labels []string
+	for _, k := range model.SortedKeys(settings.Labels) {
+		if kubeletCanSetLabel(k) {
+			labels = append(labels, fmt.Sprintf("%s=%s", k, settings.Labels[k]))
+		}
+	}
+	// network plugin pods are scheduled by it, it must be set when the node registers
+	if node.Interface != "" && len(dc.GetNodeInterfaces()) > 0 {
+		labels = append(labels, fmt.Sprintf("%s=%s", model.NetworkInterfaceLabel, node.Interface))
+	}
+	if len(labels) > 0 {
+		args["node-labels"] = strings.Join(labels, ",")
+	}
+	return args
+}
+
+// kubeletCanSetLabel reports whether the NodeRestriction admission plugin lets kubelet set the label itself.
+func kubeletCanSetLabel(key string) bool {
+	if !strings.Contains(key, "/") {
+		return true
+	}
+	namespace := key[:strings.Index(key, "/")]
+	for _, allowed := range []string{"kubelet.kubernetes.io", "node.kubernetes.io"} {
+		if namespace == allowed || strings.HasSuffix(namespace, "."+allowed) {
+			return true
+		}
+	}
+	for _, restricted := range []string{"kubernetes.io", "k8s.io"} {
+		if namespace == restricted || strings.HasSuffix(namespace, "."+restricted) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyNodeSettings converges labels and taints of the node. Labels and taints which were applied by a previous run
+// but are not defined anymore are removed, the ones added by others are not touched.
+func ApplyNodeSettings(masterIP net.IP, nodeName string, settings model.NodeSettings) {
+	prevLabels := getManaged(masterIP, nodeName, managedLabelsAnnotation)
+	prevTaints := getManaged(masterIP, nodeName, managedTaintsAnnotation)
+	var labelKeys []string
+	var labelArgs []string
+	for _, k := range model.SortedKeys(settings.Labels) {
+		labelKeys = append(labelKeys, k)
+		labelArgs = append(labelArgs, fmt.Sprintf("'%s=%s'", k, settings.Labels[k]))
+	}
+	for _, k := range prevLabels {
+		if _, exists := settings.Labels[k]; !exists {
+			labelArgs = append(labelArgs, fmt.Sprintf("'%s-'", k))
+		}
+	}
+	if len(labelArgs) > 0 {
+		os.RunCommandOn(fmt.Sprintf("kubectl label node %s %s --overwrite", nodeName, strings.Join(labelArgs, " ")),
+			masterIP, true)
+	}
+	var taintIDs []string
+	defined := make(map[string]bool)
+	for _, taint := range settings.Taints {
+		taintIDs = append(taintIDs, taint.ID())
+		defined[taint.ID()] = true
+		os.RunCommandOn(fmt.Sprintf("kubectl taint node %s '%s' --overwrite", nodeName, taint), masterIP, true)
+	}
+	for _, id := range prevTaints {
+		if !defined[id] {
+			os.RunCommandOn(fmt.Sprintf("kubectl taint node %s '%s-' || true", nodeName, id), masterIP, true)
+		}
+	}
+	os.RunCommandOn(fmt.Sprintf("kubectl annotate node %s --overwrite '%s=%s' '%s=%s'", nodeName,
+		managedLabelsAnnotation, strings.Join(labelKeys, ","), managedTaintsAnnotation, strings.Join(taintIDs, ",")),
+		masterIP, true)
+	log.Debugf("Labels and taints of \"%s\" applied", nodeName)
+}
+
+func getManaged(masterIP net.IP, nodeName string, annotation string) []string {
+	output := os.RunCommandOn(fmt.Sprintf("kubectl get node %s -o jsonpath='{.metadata.annotations.%s}'", nodeName,
+		strings.ReplaceAll(annotation, ".", "\\.")), masterIP, true)
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, ",")
+}
@@ -0,0 +1,60 @@
+package kube
+
+import (
+	"fmt"
+	"net"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+	"com.github.tunahansezen/tkube/pkg/os"
+	"github.com/hashicorp/go-version"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	PatchesDir = "/etc/kubernetes/patches"
+)
+
+// NodePatches returns the cluster patches followed by the patches of the node settings. Kubelet settings of the node
+// are added as a kubeletconfiguration patch, since KubeletConfiguration of kubeadm is shared by all nodes.
+func NodePatches(dc model.DeploymentConfig, node model.KubeNode) []model.Patch {
+	settings := dc.GetNodeSettings(node)
+	patches := append(append([]model.Patch{}, dc.Kubernetes.Patches...), settings.Patches...)
+	if settings.Kubelet != nil {
+		data, err := yaml.Marshal(settings.Kubelet)
+		if err != nil {
+			os.Exit(err.Error(), 1)
+		}
+		patches = append(patches, model.Patch{Target: "kubeletconfiguration", Type: "merge", Patch: string(data)})
+	}
+	return patches
+}
+
+// WritePatches replaces the patches directory of the node. File names follow target[suffix][+type].yaml of kubeadm,
+// the index keeps the order of patches with the same target. It is zero padded, kubeadm sorts files by name.
+func WritePatches(ip net.IP, patches []model.Patch) {
+	os.RunCommandOn(fmt.Sprintf("sudo rm -rf %s", PatchesDir), ip, true)
+	if len(patches) == 0 {
+		return
+	}
+	os.RunCommandOn(fmt.Sprintf("sudo mkdir -p %s", PatchesDir), ip, true)
+	for i, patch := range patches {
+		os.CreateFile([]byte(patch.Patch), patchFile(patch, i), ip)
+	}
+}
+
+func patchFile(patch model.Patch, i int) string {
+	return fmt.Sprintf("%s/%s%03d+%s.yaml", PatchesDir, patch.Target, i, patch.GetType())
+}
+
+// PatchesFlag returns the kubeadm flag of the patches directory, or an empty string without patches.
+func PatchesFlag(kubeVersion string, patches []model.Patch) string {
+	if len(patches) == 0 {
+		return ""
+	}
+	kubeSemVer, _ := version.NewVersion(kubeVersion)
+	kube122Ver, _ := version.NewVersion("1.22")
+	if kubeSemVer.GreaterThanOrEqual(kube122Ver) {
+		return fmt.Sprintf(" --patches %s", PatchesDir)
+	}
+	return fmt.Sprintf(" --experimental-patches %s", PatchesDir)
+}
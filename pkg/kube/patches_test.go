@@ -0,0 +1,18 @@
+package kube
+
+import (
+	"slices"
+	"testing"
+
+	"com.github.tunahansezen/tkube/pkg/config/model"
+)
+
+func TestPatchFileOrder(t *testing.T) {
+	var files []string
+	for i := 0; i < 12; i++ {
+		files = append(files, patchFile(model.Patch{Target: "kube-apiserver"}, i))
+	}
+	if !slices.IsSorted(files) {
+		t.Errorf("patch files must sort in the order of patches: %v", files)
+	}
+}
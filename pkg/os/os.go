@@ -2,6 +2,7 @@ package os
 
 import (
 	"bytes"
+	"crypto/rand"
 	"errors"
 	"fmt"
 	"net"
@@ -9,6 +10,7 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	conn "com.github.tunahansezen/tkube/pkg/connection"
@@ -22,11 +24,17 @@ import (
 )
 
 var (
-	OS                   Type
-	InstallerType        Installer
-	RemoteNodeIP         net.IP
-	RemoteNode           *conn.Node
-	sudoersPrevExistsMap = make(map[string]bool) // ip: prevExist
+	OS            Type
+	InstallerType Installer
+	RemoteNodeIP  net.IP
+	RemoteNode    *conn.Node
+	exiting       bool
+	// interruptMsg is set by the signal handler, the running command exits with it and does the cleanup
+	interruptMsg atomic.Value
+)
+
+const (
+	remoteRunAttempts = 3
 )
 
 type Type int
@@ -75,33 +83,60 @@ func RunCommandReturnError(command string, silent bool) (string, error) {
 }
 
 func runCommandOnReturnErr(command string, ip net.IP, silent, returnErr bool) (string, error) {
+	return runCommandOn(command, ip, silent, returnErr, false)
+}
+
+func runCommandOn(command string, ip net.IP, silent, returnErr, idempotent bool) (string, error) {
+	exitIfInterrupted()
 	var returnStr string
 	var err error
 	log.Tracef("CMD - ip: \"%s\" - command: \"%s\"", ip, command)
+	wrapped, stdin := wrapSudo(command, ip)
 	if ip == nil {
-		returnStr, err = localRun(command, silent)
+		returnStr, err = localRun(wrapped, silent, stdin)
 	} else {
 		node := conn.Nodes[ip.String()]
 		if node == nil {
 			node = &conn.Node{IP: ip, SSHPort: 22}
 		}
-		returnStr, err = RemoteRun(node, command, silent)
+		returnStr, err = remoteRun(node, wrapped, silent, idempotent, stdin)
 	}
 	if err != nil && !returnErr {
 		log.Debugf("ip: \"%s\" - command: \"%s\"", ip, command)
 		Exit(err.Error(), 1)
 	}
+	exitIfInterrupted()
 	log.Tracef("RETURNSTR - \"%s\"", returnStr)
 	return returnStr, err
 }
 
+// Interrupt makes tkube exit before or after the running command. Exit is not called by the signal handler, so the
+// cleanup does not run concurrently with the commands of the main goroutine.
+func Interrupt(sig os.Signal) {
+	interruptMsg.Store(fmt.Sprintf("Interrupted by %s", sig))
+}
+
+func exitIfInterrupted() {
+	if msg, interrupted := interruptMsg.Load().(string); interrupted && !exiting {
+		Exit(msg, 1)
+	}
+}
+
 func RunCommandOn(command string, ip net.IP, silent bool) string {
 	output, _ := runCommandOnReturnErr(command, ip, silent, false)
 	return output
 }
 
-func localRun(command string, silent bool) (string, error) {
+// RunIdempotentCommandOn runs command like RunCommandOn, but reconnects and runs it again if the SSH connection is
+// lost while the command is running. Only use it for commands which are safe to repeat.
+func RunIdempotentCommandOn(command string, ip net.IP, silent bool) string {
+	output, _ := runCommandOn(command, ip, silent, false, true)
+	return output
+}
+
+func localRun(command string, silent bool, stdin string) (string, error) {
 	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Stdin = strings.NewReader(stdin)
 	if !silent {
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -121,31 +156,68 @@ func localRun(command string, silent bool) (string, error) {
 }
 
 func RemoteRun(node *conn.Node, cmd string, silent bool) (string, error) {
+	return remoteRun(node, cmd, silent, false, "")
+}
+
+func remoteRun(node *conn.Node, cmd string, silent, idempotent bool, stdin string) (string, error) {
 	connection, err := conn.CreateSshConnection(node)
 	if err != nil {
 		return "", err
 	}
 
-	// create a session.
-	session, _ := connection.NewSession()
 	var bs bytes.Buffer
-	session.Stdout = &bs
 	var be bytes.Buffer
-	session.Stderr = &be
-	if !silent {
-		session.Stdout = os.Stdout
-		session.Stderr = os.Stderr
+	for attempt := 1; ; attempt++ {
+		// create a session.
+		var session *ssh.Session
+		session, err = connection.NewSession()
+		if err != nil {
+			// command has not been started yet, so it is always safe to retry on a new connection
+			if attempt >= remoteRunAttempts {
+				return "", err
+			}
+			log.Debugf("Session could not be opened on %s: %s", node.IP, err.Error())
+			connection, err = conn.Reconnect(node.IP)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+		bs.Reset()
+		be.Reset()
+		if stdin != "" {
+			session.Stdin = strings.NewReader(stdin)
+		}
+		session.Stdout = &bs
+		session.Stderr = &be
+		if !silent {
+			session.Stdout = os.Stdout
+			session.Stderr = os.Stderr
+		}
+		err = session.Run(cmd)
+		_ = session.Close()
+		if idempotent && conn.IsConnectionError(err) && attempt < remoteRunAttempts {
+			log.Debugf("Connection lost on %s while running \"%s\". Retrying", node.IP, cmd)
+			connection, err = conn.Reconnect(node.IP)
+			if err != nil {
+				return "", err
+			}
+			continue
+		}
+		break
 	}
-
-	err = session.Run(cmd)
-	if (InstallerType == Yum || InstallerType == Dnf) && err != nil &&
-		strings.Contains(cmd, "check-update") && err.(*ssh.ExitError).ExitStatus() == 100 {
+	var exitErr *ssh.ExitError
+	if (InstallerType == Yum || InstallerType == Dnf) && strings.Contains(cmd, "check-update") &&
+		errors.As(err, &exitErr) && exitErr.ExitStatus() == 100 {
 		err = nil
 	}
 	var returnStr string
 	var returnErr error
 	if err != nil {
 		errStr := strings.TrimSuffix(be.String(), "\n")
+		if errStr == "" && conn.IsConnectionError(err) {
+			errStr = err.Error()
+		}
 		returnStr, returnErr = errStr, errors.New(errStr)
 		log.Debug(returnStr)
 	} else {
@@ -154,53 +226,19 @@ func RemoteRun(node *conn.Node, cmd string, silent bool) (string, error) {
 	return returnStr, returnErr
 }
 
-func UserHomeDir() (string, error) {
-	return os.UserHomeDir()
+// RebootNode reboots the node and waits until it is reachable again with a new boot id.
+func RebootNode(ip net.IP, timeout time.Duration) {
+	bootID, err := conn.GetBootID(ip)
+	ThrowIfError(err, 1)
+	util.StartSpinner(fmt.Sprintf("Rebooting \"%s\"", ip))
+	_, _ = runCommandOnReturnErr("sudo sh -c 'sleep 2 && reboot' > /dev/null 2>&1 &", ip, true, true)
+	err = conn.WaitForNodeReboot(ip, bootID, timeout)
+	ThrowIfError(err, 1)
+	util.StopSpinner(fmt.Sprintf("\"%s\" rebooted", ip), logsymbols.Success)
 }
 
-func AddToSudoers(ip net.IP) {
-	node := conn.Nodes[ip.String()]
-	user := ""
-	pass := ""
-	if node != nil {
-		user = node.SSHUser
-		pass = node.SSHPass
-		unknownNode := &conn.Node{IP: nil, SSHUser: user, SSHPass: pass}
-		conn.Nodes["unknown"] = unknownNode
-	}
-	if user == "" || pass == "" {
-		user = RunCommandOn("whoami", ip, true)
-		dataSSHUser, dataSSHPass, _, err := conn.CheckSSHDataForAddr("")
-		if dataSSHUser != "" && dataSSHUser == user && dataSSHPass != "" {
-			pass = dataSSHPass
-		} else {
-			pass, err = util.AskString("Please enter sudo pass", true, util.PasswordValidator)
-			if err != nil {
-				Exit(err.Error(), 1)
-			}
-			err = conn.WriteSSHData("", user, pass, "")
-			if err != nil {
-				Exit(err.Error(), 1)
-			}
-		}
-	}
-	returnStr := RunCommandOn(fmt.Sprintf("echo \"%s\" | sudo -S grep -qxF '%s ALL=NOPASSWD: ALL' /etc/sudoers && "+
-		"echo exist || echo not exist", pass, user), ip, true)
-	if returnStr != "exist" {
-		sudoersPrevExistsMap[ip.String()] = false
-		RunCommandOn(fmt.Sprintf("echo \"%s\" | sudo -S sh -c \"echo '%s ALL=NOPASSWD: ALL' >> /etc/sudoers\"",
-			pass, user), ip, true)
-		log.Debugf("\"%s\" user added to sudoers", user)
-	} else {
-		sudoersPrevExistsMap[ip.String()] = true
-	}
-}
-
-func RemoveFromSudoers(ip net.IP) {
-	user := RunCommandOn("whoami", ip, true)
-	RunCommandOn(fmt.Sprintf("sudo awk '!/%s ALL=NOPASSWD: ALL/' /etc/sudoers > temp && "+
-		"sudo chown root:root temp && sudo mv temp /etc/sudoers", user), ip, true)
-	log.Debugf("\"%s\" user removed from sudoers", user)
+func UserHomeDir() (string, error) {
+	return os.UserHomeDir()
 }
 
 func AppendLineOn(line, file string, ifNotExists bool, ip net.IP) {
@@ -400,6 +438,36 @@ func InstallPackage(ps string, ip net.IP) {
 	}
 }
 
+// AvailablePackageVersions returns the versions of package p found in the repos of the node, epoch and release parts
+// are removed.
+func AvailablePackageVersions(p string, ip net.IP) []string {
+	var cmd string
+	if InstallerType == Apt {
+		cmd = "apt-cache madison %s 2>/dev/null | awk -F'|' '{ print $2 }'"
+	} else if InstallerType == Yum {
+		cmd = "sudo yum list %[1]s --showduplicates 2>/dev/null | grep ^%[1]s | awk '{ print $2 }'"
+	} else if InstallerType == Dnf {
+		cmd = "sudo dnf list %[1]s --showduplicates 2>/dev/null | grep ^%[1]s | awk '{ print $2 }'"
+	}
+	return parsePackageVersions(RunCommandOn(fmt.Sprintf(cmd, p), ip, true))
+}
+
+func parsePackageVersions(out string) []string {
+	var versions []string
+	seen := make(map[string]bool)
+	for _, v := range strings.Fields(out) {
+		if strings.Contains(v, ":") {
+			v = strings.SplitN(v, ":", 2)[1]
+		}
+		v = strings.Split(v, "-")[0]
+		if v != "" && !seen[v] {
+			seen[v] = true
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
 func getSemVer(ver string) (semVer *version.Version) {
 	var err error
 	if strings.Contains(ver, ":") {
@@ -533,7 +601,7 @@ func CreateFile(data []byte, dstFile string, ip net.IP) {
 			log.Debugf("Error occurred while writing \"%s\" file to \"%s\"", dstFile, tempDst)
 			Exit(err.Error(), 1)
 		}
-		_, err = localRun(fmt.Sprintf("sudo mv %s %s", tempDst, dstFile), true)
+		_, err = runCommandOnReturnErr(fmt.Sprintf("sudo mv %s %s", tempDst, dstFile), nil, true, true)
 		if err != nil {
 			log.Debugf("Error occurred while moving \"%s\" file to \"%s\"", dstFile, tempDst)
 			Exit(err.Error(), 1)
@@ -557,6 +625,42 @@ func CreateFile(data []byte, dstFile string, ip net.IP) {
 	}
 }
 
+// CreateSecretFile creates dstFile readable only by root. The data goes through a temporary file with a unique name
+// which is readable only by the connected user from the start.
+func CreateSecretFile(data []byte, dstFile string, ip net.IP) {
+	folder := dstFile[:strings.LastIndexAny(dstFile, "/")]
+	fileName := dstFile[strings.LastIndexAny(dstFile, "/")+1:]
+	suffix := make([]byte, 8)
+	_, err := rand.Read(suffix)
+	ThrowIfError(err, 1)
+	tempDst := fmt.Sprintf("/tmp/tkube-%x-%s", suffix, fileName)
+	RunCommandOn(fmt.Sprintf("sudo mkdir -p %s", folder), ip, true)
+	if ip == nil {
+		var f *os.File
+		f, err = os.OpenFile(tempDst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, os.FileMode(0600))
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	} else {
+		err = conn.SendSecretFile(ip, bytes.NewReader(data), tempDst)
+	}
+	if err != nil {
+		log.Debugf("Error occurred while writing \"%s\" file to \"%s\"", dstFile, tempDst)
+		RunCommandOn(fmt.Sprintf("rm -f %s", tempDst), ip, true)
+		Exit(err.Error(), 1)
+	}
+	_, err = runCommandOnReturnErr(fmt.Sprintf("sudo install -m 0600 -o root -g root %s %s && rm -f %s", tempDst,
+		dstFile, tempDst), ip, true, true)
+	if err != nil {
+		RunCommandOn(fmt.Sprintf("rm -f %s", tempDst), ip, true)
+		log.Debugf("Error occurred while moving \"%s\" file to \"%s\"", tempDst, dstFile)
+		Exit(err.Error(), 1)
+	}
+}
+
 func TransferFile(srcPath, dstPath string, from, to net.IP) (err error) {
 	folder := dstPath[:strings.LastIndexAny(dstPath, "/")]
 	if from.Equal(to) {
@@ -577,8 +681,8 @@ func TransferFile(srcPath, dstPath string, from, to net.IP) (err error) {
 			cmd = fmt.Sprintf("%s sshpass -p %s", cmd, toNode.SSHPass)
 		}
 		RunCommandOn(fmt.Sprintf("%s scp "+
-			"-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -r %s %s:%s",
-			cmd, srcPath, toNode, dstPath), from, true)
+			"-o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -r %s %s@%s:%s",
+			cmd, srcPath, toNode.SSHUser, util.URLHost(toNode.IP), dstPath), from, true)
 	}
 	return nil
 }
@@ -639,11 +743,25 @@ func ReadFile(path string, ip net.IP) (data []byte, err error) {
 	if ip == nil {
 		return os.ReadFile(path)
 	} else {
-		returnStr, err := runCommandOnReturnErr(fmt.Sprintf("sudo cat %s", path), ip, true, true)
+		returnStr, err := runCommandOn(fmt.Sprintf("sudo cat %s", path), ip, true, true, true)
 		return []byte(returnStr), err
 	}
 }
 
+// ReadRootFile reads a file which only root can access, like the ones written by CreateSecretFile. found is false if the
+// file does not exist.
+func ReadRootFile(path string, ip net.IP) (data []byte, found bool, err error) {
+	out, err := runCommandOn(fmt.Sprintf("sudo test -f %s && echo 1 || echo 0", path), ip, true, true, true)
+	if err != nil || out != "1" {
+		return nil, false, err
+	}
+	out, err = runCommandOn(fmt.Sprintf("sudo cat %s", path), ip, true, true, true)
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(out), true, nil
+}
+
 func CommandExists(command string) bool {
 	output := RunCommand(fmt.Sprintf("command -v %s | xargs", command), true)
 	return output != ""
@@ -712,13 +830,17 @@ func Exit(message string, code int) {
 			color.Red(message)
 		}
 	}
-	for addr, exists := range sudoersPrevExistsMap {
-		ip := net.ParseIP(addr)
-		if !exists {
-			RemoveFromSudoers(ip)
+	if !exiting {
+		// commands below may exit again on failure, cleanup is done only once
+		exiting = true
+		for addr, dropIn := range preparedNodes {
+			ip := net.ParseIP(addr)
+			RunCommandOn("sudo rm -f /usr/sbin/policy-rc.d || true", ip, true)
+			RunCommandOn(fmt.Sprintf("rm -rf $HOME/%s/%s", constant.CfgRootFolder, constant.TmpFolder), ip, true)
+			if dropIn {
+				removeSudoersDropIn(ip)
+			}
 		}
-		RunCommandOn("sudo rm -f /usr/sbin/policy-rc.d || true", ip, true)
-		RunCommandOn(fmt.Sprintf("rm -rf $HOME/%s/%s", constant.CfgRootFolder, constant.TmpFolder), ip, true)
 	}
 	conn.CloseSSHSessions()
 	fmt.Print("\033[?25h") // make cursor visible
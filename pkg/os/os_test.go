@@ -3,7 +3,12 @@ package os
 import (
 	"fmt"
 	"github.com/hashicorp/go-version"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestOS_Version(t *testing.T) {
@@ -14,3 +19,56 @@ func TestOS_Version(t *testing.T) {
 	}
 	fmt.Printf("sem ver: %s\n", semVer.String())
 }
+
+func TestParsePackageVersions(t *testing.T) {
+	out := " 1.34.2-1.1 \n 1.34.1-1.1 \n 1.34.1-1.1 \n 1.34.0-150500.1.1\n1:1.33.6-0\n"
+	expected := []string{"1.34.2", "1.34.1", "1.34.0", "1.33.6"}
+	actual := parsePackageVersions(out)
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestIsStaleSudoersDropIn(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		modTime time.Time
+		stale   bool
+	}{
+		{sudoersDropInPrefix + runID, now.Add(-48 * time.Hour), false},
+		{fmt.Sprintf("%s1-%d-%s", sudoersDropInPrefix, os.Getpid(), hostID), now, false},
+		{fmt.Sprintf("%s1-%d-%s", sudoersDropInPrefix, math.MaxInt32, hostID), now, true},
+		{fmt.Sprintf("%s1-%d-otherhost", sudoersDropInPrefix, math.MaxInt32), now, false},
+		{fmt.Sprintf("%s1-%d-otherhost", sudoersDropInPrefix, math.MaxInt32), now.Add(-48 * time.Hour), true},
+	}
+	for _, test := range tests {
+		if stale := isStaleSudoersDropIn(test.name, test.modTime); stale != test.stale {
+			t.Errorf("isStaleSudoersDropIn(%s) = %t, expected %t", test.name, stale, test.stale)
+		}
+	}
+}
+
+func TestReadRootFile(t *testing.T) {
+	// sudo runs the command as the test user
+	bin := t.TempDir()
+	err := os.WriteFile(filepath.Join(bin, "sudo"), []byte("#!/bin/sh\nexec \"$@\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+":"+os.Getenv("PATH"))
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	err = os.WriteFile(file, []byte("kind: EncryptionConfiguration\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, found, err := ReadRootFile(file, nil)
+	if err != nil || !found || string(data) != "kind: EncryptionConfiguration" {
+		t.Errorf("expected the file to be found, got found: %t, data: \"%s\", err: %v", found, data, err)
+	}
+	data, found, err = ReadRootFile(filepath.Join(dir, "missing.yaml"), nil)
+	if err != nil || found || data != nil {
+		t.Errorf("expected the file not to be found, got found: %t, data: \"%s\", err: %v", found, data, err)
+	}
+}
@@ -0,0 +1,199 @@
+package os
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	conn "com.github.tunahansezen/tkube/pkg/connection"
+	"com.github.tunahansezen/tkube/pkg/util"
+	log "github.com/sirupsen/logrus"
+)
+
+// Privilege escalation modes
+const (
+	EscalationSudo   = "sudo"           // every command is run with sudo, password is fed over stdin
+	EscalationDropIn = "sudoers-dropin" // a temporary passwordless rule is added to /etc/sudoers.d for this run
+	EscalationRoot   = "root"           // connected user is root, nothing to do
+)
+
+const (
+	sudoersDropInDir    = "/etc/sudoers.d"
+	sudoersDropInPrefix = "tkube-"
+	// drop-ins of runs started on other hosts can not be checked by pid, they are removed after this age
+	staleSudoersDropInAge = 24 * time.Hour
+	// sudoWrapper reads the password from stdin into a shell variable and shadows sudo, so every sudo call of the
+	// command gets the password as the first line of its stdin. Commands which read stdin get the rest of it.
+	sudoWrapper = "IFS= read -r TKUBE_SUDO_PASS; " +
+		"sudo() { { printf '%s\\n' \"$TKUBE_SUDO_PASS\"; cat; } | command sudo -k -S -p '' \"$@\"; }; "
+)
+
+var (
+	PrivilegeEscalation = EscalationDropIn
+	hostID              = localHostID()
+	// runID is "<start time>-<pid>-<host id>", sudo skips drop-ins having "." in their names
+	runID         = fmt.Sprintf("%d-%d-%s", time.Now().Unix(), os.Getpid(), hostID)
+	sudoPasswords = make(map[string]string) // ip: pass, only for nodes needing the sudo wrapper
+	preparedNodes = make(map[string]bool)   // ip: drop-in created
+)
+
+func ValidatePrivilegeEscalation() error {
+	switch PrivilegeEscalation {
+	case EscalationSudo, EscalationDropIn, EscalationRoot:
+		return nil
+	}
+	return fmt.Errorf("unknown privilege escalation mode \"%s\", valid modes: %s, %s, %s", PrivilegeEscalation,
+		EscalationSudo, EscalationDropIn, EscalationRoot)
+}
+
+// PreparePrivilegeEscalation makes sure that commands prefixed with sudo can run on ip with the selected mode.
+func PreparePrivilegeEscalation(ip net.IP) {
+	if _, prepared := preparedNodes[ip.String()]; prepared {
+		return
+	}
+	if PrivilegeEscalation == EscalationRoot {
+		uid := RunCommandOn("id -u", ip, true)
+		if uid != "0" {
+			Exit(fmt.Sprintf("Privilege escalation mode is \"%s\" but connected user is not root on \"%s\"",
+				EscalationRoot, ip), 1)
+		}
+		preparedNodes[ip.String()] = false
+		return
+	}
+	// a drop-in left by a killed run would make passwordless sudo look allowed, it is removed first
+	removeStaleSudoersDropIns(ip)
+	if _, err := runCommandOnReturnErr("sudo -n true", ip, true, true); err == nil {
+		log.Debugf("Passwordless sudo is already allowed on \"%s\"", ip)
+		preparedNodes[ip.String()] = false
+		return
+	}
+	user := RunCommandOn("whoami", ip, true)
+	pass, err := getSudoPass(ip, user)
+	if err != nil {
+		Exit(err.Error(), 1)
+	}
+	sudoPasswords[ip.String()] = pass
+	if _, err = runCommandOnReturnErr("sudo true", ip, true, true); err != nil {
+		delete(sudoPasswords, ip.String())
+		Exit(fmt.Sprintf("sudo password is wrong for user \"%s\" on \"%s\"", user, ip), 1)
+	}
+	if PrivilegeEscalation == EscalationSudo {
+		preparedNodes[ip.String()] = false
+		return
+	}
+	addSudoersDropIn(ip, user)
+	delete(sudoPasswords, ip.String())
+	preparedNodes[ip.String()] = true
+}
+
+func getSudoPass(ip net.IP, user string) (string, error) {
+	if node := conn.Nodes[ip.String()]; node != nil && node.GetSudoPass() != "" {
+		return node.GetSudoPass(), nil
+	}
+	dataSSHUser, dataSSHPass, _, err := conn.CheckSSHDataForAddr("")
+	if err != nil {
+		return "", err
+	}
+	if dataSSHUser != "" && dataSSHUser == user && dataSSHPass != "" {
+		return dataSSHPass, nil
+	}
+	pass, err := util.AskString(fmt.Sprintf("Please enter sudo pass of \"%s\"", user), true, util.PasswordValidator)
+	if err != nil {
+		return "", err
+	}
+	return pass, conn.WriteSSHData("", user, pass, "")
+}
+
+// addSudoersDropIn validates and installs a passwordless rule for user, only for this run.
+func addSudoersDropIn(ip net.IP, user string) {
+	_, err := runCommandOnReturnErr(fmt.Sprintf("sudo grep -Eq '^[#@]includedir[[:space:]]+%s' /etc/sudoers",
+		sudoersDropInDir), ip, true, true)
+	if err != nil {
+		Exit(fmt.Sprintf("\"%s\" is not included by /etc/sudoers on \"%s\", use --privilege-escalation %s",
+			sudoersDropInDir, ip, EscalationSudo), 1)
+	}
+	dropIn := fmt.Sprintf("%s/%s%s", sudoersDropInDir, sudoersDropInPrefix, runID)
+	tmpFile := fmt.Sprintf("/tmp/%s%s", sudoersDropInPrefix, runID)
+	RunCommandOn(fmt.Sprintf("echo '%s ALL=(ALL) NOPASSWD: ALL' > %s", user, tmpFile), ip, true)
+	_, err = runCommandOnReturnErr(fmt.Sprintf("sudo visudo -cf %s", tmpFile), ip, true, true)
+	if err != nil {
+		RunCommandOn(fmt.Sprintf("rm -f %s", tmpFile), ip, true)
+		Exit(fmt.Sprintf("sudoers drop-in could not be validated on \"%s\": %s", ip, err.Error()), 1)
+	}
+	RunCommandOn(fmt.Sprintf("sudo install -m 0440 -o root -g root %s %s && rm -f %s", tmpFile, dropIn, tmpFile),
+		ip, true)
+	log.Debugf("Sudoers drop-in \"%s\" added for \"%s\" on \"%s\"", dropIn, user, ip)
+}
+
+// removeStaleSudoersDropIns removes drop-ins of tkube runs which are not running anymore. Drop-ins of concurrent
+// runs are kept. Listing needs passwordless sudo, without it no drop-in is active for the connected user.
+func removeStaleSudoersDropIns(ip net.IP) {
+	out, err := runCommandOnReturnErr(fmt.Sprintf("sudo -n find %s -maxdepth 1 -name '%s*' -printf '%%f %%T@\\n'",
+		sudoersDropInDir, sudoersDropInPrefix), ip, true, true)
+	if err != nil {
+		return
+	}
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		modTime, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || !isStaleSudoersDropIn(fields[0], time.Unix(int64(modTime), 0)) {
+			continue
+		}
+		RunCommandOn(fmt.Sprintf("sudo -n rm -f %s/%s", sudoersDropInDir, fields[0]), ip, true)
+		log.Debugf("Stale sudoers drop-in \"%s\" removed on \"%s\"", fields[0], ip)
+	}
+}
+
+// isStaleSudoersDropIn reports whether the run which created the drop-in has ended. Runs of this host are checked by
+// their pid, the others by the age of the drop-in.
+func isStaleSudoersDropIn(name string, modTime time.Time) bool {
+	if name == sudoersDropInPrefix+runID {
+		return false
+	}
+	if time.Since(modTime) > staleSudoersDropInAge {
+		return true
+	}
+	parts := strings.Split(strings.TrimPrefix(name, sudoersDropInPrefix), "-")
+	if len(parts) != 3 || parts[2] != hostID {
+		return false
+	}
+	pid, err := strconv.Atoi(parts[1])
+	return err == nil && !processRunning(pid)
+}
+
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func localHostID() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(hostname)))[:8]
+}
+
+func removeSudoersDropIn(ip net.IP) {
+	dropIn := fmt.Sprintf("%s/%s%s", sudoersDropInDir, sudoersDropInPrefix, runID)
+	_, err := runCommandOnReturnErr(fmt.Sprintf("sudo rm -f %s", dropIn), ip, true, true)
+	if err != nil {
+		log.Errorf("Sudoers drop-in \"%s\" could not be removed on \"%s\"", dropIn, ip)
+		return
+	}
+	log.Debugf("Sudoers drop-in \"%s\" removed on \"%s\"", dropIn, ip)
+}
+
+// wrapSudo returns the command to run and what to write to its stdin.
+func wrapSudo(command string, ip net.IP) (string, string) {
+	pass, exists := sudoPasswords[ip.String()]
+	if !exists || !strings.Contains(command, "sudo") {
+		return command, ""
+	}
+	return sudoWrapper + command, pass + "\n"
+}
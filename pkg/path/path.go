@@ -1,20 +1,32 @@
 package path
 
 import (
+	"errors"
+	"fmt"
+	"net"
+	goos "os"
+	"regexp"
+	"sort"
+	"strings"
+
 	conn "com.github.tunahansezen/tkube/pkg/connection"
 	"com.github.tunahansezen/tkube/pkg/constant"
 	"com.github.tunahansezen/tkube/pkg/os"
-	"fmt"
-	"net"
 )
 
 var (
+	ClusterName               string // --cluster
+	ConfigFile                string // --config
 	homePath                  string
+	localMainDir              string
 	tkubeMainDir              string
 	tkubeCfgDir               string
+	tkubeStateDir             string
 	tkubeResourcesDir         string
 	tkubeTmpDir               string
 	tkubeEtcdRecoveryCertsDir string
+	tkubeLogsDir              string
+	clusterNameRegex          = regexp.MustCompile("^[a-z0-9][a-z0-9_.-]*$")
 )
 
 func GetTKubeMainDir() string {
@@ -25,6 +37,10 @@ func GetTKubeCfgDir() string {
 	return tkubeCfgDir
 }
 
+func GetTKubeStateDir() string {
+	return tkubeStateDir
+}
+
 func GetTKubeResourcesDir() string {
 	return tkubeResourcesDir
 }
@@ -43,11 +59,145 @@ func GetTKubeEtcdRecoveryCertsDir() string {
 	return tkubeEtcdRecoveryCertsDir
 }
 
+func GetTKubeLogsDir() string {
+	return tkubeLogsDir
+}
+
+// GetKubeadmCfgFile returns the kubeadm config file kept on the first master.
+func GetKubeadmCfgFile() string {
+	return fmt.Sprintf("%s/%s", tkubeStateDir, constant.KubeadmCfgFile)
+}
+
+// GetKubeadmCfgFileOnMaster returns a shell expression of the kubeadm config file, masters of clusters installed by older
+// versions keep it next to the deployment config.
+func GetKubeadmCfgFileOnMaster() string {
+	legacyFile := fmt.Sprintf("%s/%s/%s", tkubeMainDir, constant.CfgFolder, constant.KubeadmCfgFile)
+	return fmt.Sprintf("$([ -f %s ] || [ ! -f %s ] && echo %s || echo %s)", GetKubeadmCfgFile(), legacyFile,
+		GetKubeadmCfgFile(), legacyFile)
+}
+
+// GetDeploymentCfgFile returns the file given with --config, or the deployment config of the cluster.
+func GetDeploymentCfgFile() string {
+	if ConfigFile != "" {
+		return ConfigFile
+	}
+	return fmt.Sprintf("%s/%s.%s", tkubeCfgDir, constant.DeploymentCfgName, constant.DefaultCfgType)
+}
+
+// ResolveCluster selects the cluster to work on and sets the local paths of it. The selection, credential store and
+// logs of clusters are kept on this machine, so it does not need any connection.
+func ResolveCluster() error {
+	home, err := goos.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	localMainDir = fmt.Sprintf("%s/%s", home, constant.CfgRootFolder)
+	if ClusterName == "" {
+		ClusterName = GetCurrentCluster()
+	}
+	err = ValidateClusterName(ClusterName)
+	if err != nil {
+		return err
+	}
+	localClusterDir := fmt.Sprintf("%s/%s/%s", localMainDir, constant.ClustersFolder, ClusterName)
+	tkubeLogsDir = fmt.Sprintf("%s/%s", localClusterDir, constant.LogsFolder)
+	credentialsFile := fmt.Sprintf("%s/%s", localClusterDir, constant.CredentialsFile)
+	legacyCredentialsFile := fmt.Sprintf("%s/%s", localMainDir, constant.LegacyCredentialsFile)
+	if ClusterName == constant.DefaultClusterName && !fileExists(credentialsFile) && fileExists(legacyCredentialsFile) {
+		err = goos.MkdirAll(localClusterDir, goos.FileMode(0700))
+		if err != nil {
+			return err
+		}
+		err = goos.Rename(legacyCredentialsFile, credentialsFile)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Credential store moved to \"%s\"\n", credentialsFile)
+	}
+	conn.SetSSHDataFile(credentialsFile)
+	return nil
+}
+
+func ValidateClusterName(name string) error {
+	if !clusterNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid cluster name \"%s\", use lowercase letters, numbers, '-', '_' and '.'", name)
+	}
+	return nil
+}
+
+func GetCurrentCluster() string {
+	data, err := goos.ReadFile(fmt.Sprintf("%s/%s", localMainDir, constant.CurrentClusterFile))
+	if err != nil || strings.TrimSpace(string(data)) == "" {
+		return constant.DefaultClusterName
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func SetCurrentCluster(name string) error {
+	err := ValidateClusterName(name)
+	if err != nil {
+		return err
+	}
+	err = goos.MkdirAll(localMainDir, goos.FileMode(0700))
+	if err != nil {
+		return err
+	}
+	return goos.WriteFile(fmt.Sprintf("%s/%s", localMainDir, constant.CurrentClusterFile), []byte(name+"\n"),
+		goos.FileMode(0644))
+}
+
+// ListClusters returns the clusters known on this machine.
+func ListClusters() ([]string, error) {
+	entries, err := goos.ReadDir(fmt.Sprintf("%s/%s", localMainDir, constant.ClustersFolder))
+	if errors.Is(err, goos.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var clusters []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			clusters = append(clusters, entry.Name())
+		}
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
 func CalculatePaths() {
 	homePath = os.RunCommand("echo $HOME", true)
 	tkubeMainDir = fmt.Sprintf("%s/%s", homePath, constant.CfgRootFolder)
-	tkubeCfgDir = fmt.Sprintf("%s/%s", tkubeMainDir, constant.CfgFolder)
+	clusterDir := fmt.Sprintf("%s/%s/%s", tkubeMainDir, constant.ClustersFolder, ClusterName)
+	tkubeCfgDir = fmt.Sprintf("%s/%s", clusterDir, constant.CfgFolder)
+	tkubeStateDir = fmt.Sprintf("%s/%s", clusterDir, constant.StateFolder)
 	tkubeResourcesDir = fmt.Sprintf("%s/%s", tkubeMainDir, constant.ResourcesFolder)
 	tkubeTmpDir = fmt.Sprintf("%s/%s", tkubeMainDir, constant.TmpFolder)
-	tkubeEtcdRecoveryCertsDir = fmt.Sprintf("%s/%s", tkubeMainDir, constant.EtcdRecoveryCertFolder)
+	tkubeEtcdRecoveryCertsDir = fmt.Sprintf("%s/%s/%s", clusterDir, constant.PkiFolder,
+		constant.EtcdRecoveryCertFolder)
+	if ClusterName == constant.DefaultClusterName {
+		migrateLegacyLayout(clusterDir)
+	}
+}
+
+// migrateLegacyLayout moves the single cluster layout of older versions to the default cluster.
+func migrateLegacyLayout(clusterDir string) {
+	legacyCfgDir := fmt.Sprintf("%s/%s", tkubeMainDir, constant.CfgFolder)
+	legacyRecoveryDir := fmt.Sprintf("%s/%s", tkubeMainDir, strings.Split(constant.EtcdRecoveryCertFolder, "/")[0])
+	if os.RunCommand(fmt.Sprintf("[ -d %s ] && [ ! -d %s ] && echo 1 || echo 0", legacyCfgDir, tkubeCfgDir),
+		true) != "1" {
+		return
+	}
+	os.RunCommand(fmt.Sprintf("mkdir -p %s/%s %s && mv %s %s", clusterDir, constant.PkiFolder, tkubeStateDir,
+		legacyCfgDir, tkubeCfgDir), true)
+	// kubeadm config was kept next to deployment config
+	os.RunCommand(fmt.Sprintf("[ ! -f %s/%s ] || mv %s/%s %s/", tkubeCfgDir, constant.KubeadmCfgFile, tkubeCfgDir,
+		constant.KubeadmCfgFile, tkubeStateDir), true)
+	os.RunCommand(fmt.Sprintf("[ ! -d %s ] || mv %s %s/%s/", legacyRecoveryDir, legacyRecoveryDir, clusterDir,
+		constant.PkiFolder), true)
+	fmt.Printf("Config of \"%s\" moved to cluster \"%s\"\n", tkubeMainDir, constant.DefaultClusterName)
+}
+
+func fileExists(path string) bool {
+	_, err := goos.Stat(path)
+	return err == nil
 }
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContext = 2
+
+// Diff returns a line based diff of a and b in unified format without file headers.
+func Diff(a, b []byte) string {
+	aLines := strings.Split(strings.TrimSuffix(string(a), "\n"), "\n")
+	bLines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of aLines[i:] and bLines[j:]
+	lcs := make([][]int, len(aLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bLines)+1)
+	}
+	for i := len(aLines) - 1; i >= 0; i-- {
+		for j := len(bLines) - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(aLines) || j < len(bLines) {
+		if i < len(aLines) && j < len(bLines) && aLines[i] == bLines[j] {
+			lines = append(lines, line{' ', aLines[i]})
+			i++
+			j++
+		} else if j < len(bLines) && (i == len(aLines) || lcs[i][j+1] >= lcs[i+1][j]) {
+			lines = append(lines, line{'+', bLines[j]})
+			j++
+		} else {
+			lines = append(lines, line{'-', aLines[i]})
+			i++
+		}
+	}
+	var sb strings.Builder
+	lastPrinted := -1
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		start := max(k-diffContext, lastPrinted+1)
+		if lastPrinted >= 0 && start > lastPrinted+1 {
+			sb.WriteString("...\n")
+		}
+		for c := start; c < k; c++ {
+			sb.WriteString(fmt.Sprintf(" %s\n", lines[c].text))
+		}
+		sb.WriteString(fmt.Sprintf("%c%s\n", l.op, l.text))
+		lastPrinted = k
+		for c := k + 1; c < len(lines) && c <= k+diffContext && lines[c].op == ' '; c++ {
+			sb.WriteString(fmt.Sprintf(" %s\n", lines[c].text))
+			lastPrinted = c
+		}
+	}
+	return sb.String()
+}
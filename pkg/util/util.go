@@ -15,8 +15,7 @@ import (
 )
 
 const (
-	WaitSleep     = 5 * time.Second
-	LocalDataPath = "$HOME/.tkube/data"
+	WaitSleep = 5 * time.Second
 )
 
 var (
@@ -85,11 +84,21 @@ var (
 		if len(input) < 1 {
 			return errors.New("need some input")
 		}
-		// IP or hostname with optional port
-		regex := `^(([a-zA-Z0-9-]+\.)*[a-zA-Z0-9-]+|\d{1,3}(\.\d{1,3}){3})(:\d{1,5})?$`
-		match, _ := regexp.MatchString(regex, input)
-		if !match {
-			return errors.New("invalid address format (allowed: hostname, IPv4, and optional port)")
+		// IP or hostname with optional port, IPv6 addresses are bracketed when a port is given
+		if net.ParseIP(input) != nil {
+			return nil
+		}
+		host := input
+		if h, port, err := net.SplitHostPort(input); err == nil {
+			if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+				return errors.New("invalid port")
+			}
+			host = h
+		}
+		regex := `^(([a-zA-Z0-9-]+\.)*[a-zA-Z0-9-]+|\d{1,3}(\.\d{1,3}){3})$`
+		match, _ := regexp.MatchString(regex, host)
+		if !match && net.ParseIP(host) == nil {
+			return errors.New("invalid address format (allowed: hostname, IPv4, [IPv6], and optional port)")
 		}
 		return nil
 	}
@@ -207,3 +216,11 @@ func GetLastNonEmptyLine(s string) string {
 	}
 	return ""
 }
+
+// URLHost returns ip as it is written in URLs and host:port, IPv6 addresses are enclosed in brackets.
+func URLHost(ip net.IP) string {
+	if ip.To4() == nil {
+		return fmt.Sprintf("[%s]", ip)
+	}
+	return ip.String()
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+)
+
+func TestAddressValidator(t *testing.T) {
+	tests := []struct {
+		input string
+		valid bool
+	}{
+		{"192.168.50.10", true},
+		{"192.168.50.10:6443", true},
+		{"fd00:50::10", true},
+		{"[fd00:50::10]:6443", true},
+		{"master1", true},
+		{"master1.example.com:6443", true},
+		{"", false},
+		{"master1:0", false},
+		{"master1:65536", false},
+		{"master1:https", false},
+		{"[fd00:50::10]:abc", false},
+		{"master_1", false},
+		{"master1 example.com", false},
+	}
+	for _, test := range tests {
+		if err := AddressValidator(test.input); (err == nil) != test.valid {
+			t.Errorf("AddressValidator(%s) = %v, expected valid: %t", test.input, err, test.valid)
+		}
+	}
+}